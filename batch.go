@@ -0,0 +1,93 @@
+package goignore
+
+// The verdicts of the directories seen while matching a batch of paths, as a tree
+// Siblings share the verdicts of their parents, so each directory is only matched once
+type dirVerdict struct {
+	// the rule which ignores the directory or one of its parents, nil if none of them are ignored
+	ignoredBy *rule
+	children  map[string]*dirVerdict
+}
+
+// Same as decidingRule, but reuses and records the verdicts of the parent directories
+// lastMatchingRule is called with the path and its prefixes, which must be prepared for matching (folded, relative to the base)
+func (root *dirVerdict) decidingRule(pathComponents []string, isDir bool, lastMatchingRule func(isDir bool, pathComponents []string) *rule) *rule {
+	node := root
+	for j := 0; j < len(pathComponents)-1; j++ {
+		child, ok := node.children[pathComponents[j]]
+		if !ok {
+			// If a parent directory is ignored, nothing inside of it can be re-included
+			child = &dirVerdict{}
+			if rule := lastMatchingRule(true, pathComponents[:j+1]); rule != nil && !rule.Negate {
+				child.ignoredBy = rule
+			}
+
+			if node.children == nil {
+				node.children = make(map[string]*dirVerdict)
+			}
+			node.children[pathComponents[j]] = child
+		}
+
+		if child.ignoredBy != nil {
+			return child.ignoredBy
+		}
+		node = child
+	}
+
+	return lastMatchingRule(isDir, pathComponents)
+}
+
+// Same as calling MatchesPath for each path, but the verdicts of parent directories are shared between the paths
+// Checking a whole listing, e.g. the output of `git ls-files`, takes time roughly linear in its size
+func (g *GitIgnore) MatchPaths(paths []string) []bool {
+	matches := make([]bool, len(paths))
+	dirs := &dirVerdict{}
+
+	for i, path := range paths {
+		pathComponents, isDir, ok := splitPath(path)
+		if !ok {
+			continue
+		}
+		if g.caseFold {
+			foldPathComponents(pathComponents)
+		}
+		pathComponents, ok = g.relativeToBase(pathComponents)
+		if !ok {
+			continue
+		}
+
+		rule := dirs.decidingRule(pathComponents, isDir, g.lastMatchingRule)
+		matches[i] = rule != nil && !rule.Negate
+	}
+	return matches
+}
+
+// Same as calling MatchesPath for each path, but the verdicts of parent directories are shared between the paths
+func (s *Stack) MatchPaths(paths []string) []bool {
+	matches := make([]bool, len(paths))
+	dirs := &dirVerdict{}
+
+	for i, path := range paths {
+		pathComponents, isDir, ok := splitPath(path)
+		if !ok {
+			continue
+		}
+
+		folded := s.fold(pathComponents)
+		lastMatchingRule := func(isDir bool, prefix []string) *rule {
+			var foldedPrefix []string
+			if folded != nil {
+				foldedPrefix = folded[:len(prefix)]
+			}
+			return s.lastMatchingRule(isDir, prefix, foldedPrefix)
+		}
+
+		rule := dirs.decidingRule(pathComponents, isDir, lastMatchingRule)
+		matches[i] = rule != nil && !rule.Negate
+	}
+	return matches
+}
+
+// Same as calling MatchesPath for each path, but the verdicts of parent directories are shared between the paths
+func (r *Repository) MatchPaths(paths []string) []bool {
+	return r.stack.MatchPaths(paths)
+}
@@ -0,0 +1,120 @@
+package goignore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var batchPaths = []string{
+	"a.o",
+	"keep.o",
+	"src/main.c",
+	"src/main.o",
+	"src/keep.o",
+	"build/",
+	"build/out",
+	"build/keep.o",
+	"src/build/x/y.c",
+	"src/build/",
+	"Src/Build/z",
+	"docs/a/b/c/d.md",
+	"docs/a/b/c/d.o",
+	"./src/../a.o",
+	"../outside",
+	"a/\x00",
+	"",
+}
+
+// MatchPaths must give the same answers as MatchesPath, whatever order the paths come in
+func TestMatchPaths(t *testing.T) {
+	for _, lines := range [][]string{
+		{"*.o", "!keep.o", "build/"},
+		{"/*", "!/src", "/src/*", "!/src/build"},
+		{"docs/**/*.md", "!docs/a/", "b/"},
+		{"*", "!*/", "!*.c"},
+		{},
+	} {
+		ignoreObject := CompileIgnoreLines(lines...)
+		folded, err := Compile(lines, WithCaseFold(), WithBaseDir("SRC"))
+		assert.NoError(t, err)
+
+		for _, paths := range [][]string{batchPaths, reversed(batchPaths)} {
+			matches := ignoreObject.MatchPaths(paths)
+			foldedMatches := folded.MatchPaths(paths)
+			for i, path := range paths {
+				assert.Equal(t, ignoreObject.MatchesPath(path), matches[i], "%v: %q", lines, path)
+				assert.Equal(t, folded.MatchesPath(path), foldedMatches[i], "%v folded: %q", lines, path)
+			}
+		}
+	}
+}
+
+func TestStack_MatchPaths(t *testing.T) {
+	folded, err := Compile([]string{"*.O"}, WithCaseFold())
+	assert.NoError(t, err)
+	stack := NewStack(
+		folded,
+		CompileIgnoreLines("!keep.o", "build/"),
+	)
+
+	matches := stack.MatchPaths(batchPaths)
+	for i, path := range batchPaths {
+		assert.Equal(t, stack.MatchesPath(path), matches[i], "%q", path)
+	}
+}
+
+func TestRepository_MatchPaths(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".gitignore":     "*.o\n",
+		"src/.gitignore": "!keep.o\nbuild/\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+
+	matches := repo.MatchPaths(batchPaths)
+	for i, path := range batchPaths {
+		assert.Equal(t, repo.MatchesPath(path), matches[i], "%q", path)
+	}
+}
+
+func reversed(paths []string) []string {
+	result := make([]string, len(paths))
+	for i, path := range paths {
+		result[len(paths)-1-i] = path
+	}
+	return result
+}
+
+// A listing with many files in few directories, like most source trees
+func benchmarkListing() []string {
+	var paths []string
+	for i := 0; i < 100; i++ {
+		dir := "src/pkg" + strconv.Itoa(i) + "/internal/"
+		for j := 0; j < 50; j++ {
+			paths = append(paths, dir+"file"+strconv.Itoa(j)+".go")
+		}
+	}
+	return paths
+}
+
+func BenchmarkMatchPaths(b *testing.B) {
+	ignoreObject := CompileIgnoreLines("*.o", "!keep.o", "build/", "/vendor", "**/testdata/**", "*.log", "node_modules/")
+	paths := benchmarkListing()
+
+	b.Run("MatchesPath", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, path := range paths {
+				ignoreObject.MatchesPath(path)
+			}
+		}
+	})
+
+	b.Run("MatchPaths", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ignoreObject.MatchPaths(paths)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package goignore
+
+import (
+	"container/list"
+	"sync"
+)
+
+// A bounded cache of directory verdicts, safe for concurrent use
+// The least recently used directory is evicted when it is full
+type dirCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	// most recently used first, the values are *dirCacheEntry
+	order *list.List
+}
+
+type dirCacheEntry struct {
+	dir string
+	// the rule which ignores the directory or one of its parents, nil if none of them are ignored
+	ignoredBy *rule
+}
+
+func newDirCache(capacity int) *dirCache {
+	return &dirCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// dir is a []byte so looking it up doesn't allocate
+func (c *dirCache) get(dir []byte) (ignoredBy *rule, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[string(dir)]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*dirCacheEntry).ignoredBy, true
+}
+
+func (c *dirCache) put(dir string, ignoredBy *rule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[dir]; ok {
+		element.Value.(*dirCacheEntry).ignoredBy = ignoredBy
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*dirCacheEntry).dir)
+	}
+	c.entries[dir] = c.order.PushFront(&dirCacheEntry{dir: dir, ignoredBy: ignoredBy})
+}
+
+func (c *dirCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *dirCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+// Appends the components joined by '/' to buf
+func appendJoinedPath(buf []byte, pathComponents []string) []byte {
+	for i, component := range pathComponents {
+		if i > 0 {
+			buf = append(buf, '/')
+		}
+		buf = append(buf, component...)
+	}
+	return buf
+}
+
+// Returns the rule which ignores the directory or one of its parents, nil if none of them are ignored
+// The directory must be prepared for matching (folded, relative to the base), the verdicts of it and its parents are cached
+// key is the directory joined by '/', see appendJoinedPath()
+func (g *GitIgnore) cachedDirVerdict(dir []string, key []byte) *rule {
+	if ignoredBy, ok := g.dirCache.get(key); ok {
+		return ignoredBy
+	}
+
+	var ignoredBy *rule
+	if len(dir) > 1 {
+		parentKey := key[:len(key)-len(dir[len(dir)-1])-1]
+		ignoredBy = g.cachedDirVerdict(dir[:len(dir)-1], parentKey)
+	}
+	if ignoredBy == nil {
+		if rule := g.lastMatchingRule(true, dir); rule != nil && !rule.Negate {
+			ignoredBy = rule
+		}
+	}
+
+	g.dirCache.put(string(key), ignoredBy)
+	return ignoredBy
+}
+
+// Empties the directory cache enabled by WithDirCache(), it does nothing if the cache is not enabled
+func (g *GitIgnore) ClearCache() {
+	if g.dirCache != nil {
+		g.dirCache.clear()
+	}
+}
@@ -0,0 +1,127 @@
+package goignore
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The cache must not change any answers, even when it is too small to hold every directory
+func TestDirCache(t *testing.T) {
+	for _, lines := range [][]string{
+		{"*.o", "!keep.o", "build/"},
+		{"/*", "!/src", "/src/*", "!/src/build"},
+		{"docs/**/*.md", "!docs/a/", "b/"},
+		{"*", "!*/", "!*.c"},
+	} {
+		ignoreObject := CompileIgnoreLines(lines...)
+		for _, size := range []int{1, 2, 1000} {
+			cached, err := Compile(lines, WithDirCache(size))
+			assert.NoError(t, err)
+
+			// twice, so the second round is answered from the cache
+			for round := 0; round < 2; round++ {
+				for _, path := range batchPaths {
+					assert.Equal(t, ignoreObject.MatchesPath(path), cached.MatchesPath(path), "%v size %d: %q", lines, size, path)
+					assert.Equal(t, ignoreObject.Explain(path), cached.Explain(path), "%v size %d: %q", lines, size, path)
+				}
+			}
+			assert.LessOrEqual(t, cached.dirCache.len(), size)
+		}
+	}
+}
+
+func TestDirCache_Eviction(t *testing.T) {
+	cache := newDirCache(2)
+	first, second := &rule{Pattern: "first"}, &rule{Pattern: "second"}
+
+	cache.put("a", first)
+	cache.put("b", nil)
+	_, ok := cache.get([]byte("a")) // a is now more recently used than b
+	assert.True(t, ok)
+	cache.put("c", second)
+
+	ignoredBy, ok := cache.get([]byte("a"))
+	assert.True(t, ok)
+	assert.Equal(t, first, ignoredBy)
+	_, ok = cache.get([]byte("b"))
+	assert.False(t, ok, "b should have been evicted")
+	ignoredBy, ok = cache.get([]byte("c"))
+	assert.True(t, ok)
+	assert.Equal(t, second, ignoredBy)
+
+	cache.put("c", nil)
+	ignoredBy, ok = cache.get([]byte("c"))
+	assert.True(t, ok)
+	assert.Nil(t, ignoredBy)
+	assert.Equal(t, 2, cache.len())
+}
+
+func TestClearCache(t *testing.T) {
+	ignoreObject, err := Compile([]string{"build/"}, WithDirCache(10))
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/build/x/y"), "a/build/x/y should match")
+	assert.Equal(t, 3, ignoreObject.dirCache.len())
+
+	ignoreObject.ClearCache()
+	assert.Equal(t, 0, ignoreObject.dirCache.len())
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/build/x/y"), "a/build/x/y should still match")
+
+	// does nothing without a cache
+	CompileIgnoreLines("a").ClearCache()
+}
+
+func TestDirCache_Options(t *testing.T) {
+	ignoreObject, err := Compile([]string{"/Build"}, WithDirCache(10), WithCaseFold(), WithBaseDir("Sub"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("sub/BUILD/x"), "sub/BUILD/x should match")
+	assert.Equal(t, true, ignoreObject.MatchesPath("Sub/build/x"), "Sub/build/x should match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/x"), "build/x should not match")
+	assert.Equal(t, 1, ignoreObject.dirCache.len(), "the cache should only hold build")
+
+	ignoreObject, err = Compile([]string{"a"}, WithDirCache(0))
+	assert.NoError(t, err)
+	assert.Nil(t, ignoreObject.dirCache, "a size of 0 should disable the cache")
+}
+
+func TestDirCache_Concurrent(t *testing.T) {
+	ignoreObject, err := Compile([]string{"*.o", "!keep.o", "build/"}, WithDirCache(8))
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				dir := "d" + strconv.Itoa((worker+i)%16)
+				assert.Equal(t, true, ignoreObject.MatchesPath(dir+"/build/keep.o"))
+				assert.Equal(t, false, ignoreObject.MatchesPath(dir+"/src/keep.o"))
+				if i%50 == 0 {
+					ignoreObject.ClearCache()
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+func BenchmarkDirCache(b *testing.B) {
+	lines := []string{"*.o", "!keep.o", "build/", "/vendor", "**/testdata/**", "*.log", "node_modules/"}
+	paths := benchmarkListing()
+
+	for _, size := range []int{0, 4096} {
+		ignoreObject, _ := Compile(lines, WithDirCache(size))
+		b.Run("size="+strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, path := range paths {
+					ignoreObject.MatchesPath(path)
+				}
+			}
+		})
+	}
+}
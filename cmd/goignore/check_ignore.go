@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/botondmester/goignore"
+)
+
+const checkIgnoreUsage = `usage: goignore check-ignore [<options>] <pathname>...
+   or: goignore check-ignore [<options>] --stdin
+
+    -q, --quiet           suppress progress reporting
+    -v, --verbose         be verbose
+
+    --stdin               read file names from stdin
+    -z                    terminate input and output records by a NUL character
+    -n, --non-matching    show non-matching input paths
+    --no-index            ignore index when checking (the index is never read)
+`
+
+// Same as `git check-ignore`, with the same output and exit codes
+// The index is never read, so tracked files are reported like with --no-index
+func checkIgnore(e *env, args []string) int {
+	var quiet, verbose, stdin, nulTerminated, nonMatching, noIndex bool
+	paths, code, ok := e.parseCommandLine(checkIgnoreUsage, args, map[string]*bool{
+		"q":            &quiet,
+		"quiet":        &quiet,
+		"v":            &verbose,
+		"verbose":      &verbose,
+		"stdin":        &stdin,
+		"z":            &nulTerminated,
+		"n":            &nonMatching,
+		"non-matching": &nonMatching,
+		"no-index":     &noIndex,
+	})
+	if !ok {
+		return code
+	}
+
+	// the same checks, in the same order, as git
+	if stdin {
+		if len(paths) > 0 {
+			return e.fatal("cannot specify pathnames with --stdin")
+		}
+	} else {
+		if nulTerminated {
+			return e.fatal("-z only makes sense with --stdin")
+		}
+		if len(paths) == 0 {
+			return e.fatal("no path specified")
+		}
+	}
+	if quiet {
+		if len(paths) > 1 {
+			return e.fatal("--quiet is only valid with a single pathname")
+		}
+		if verbose {
+			return e.fatal("cannot have both --quiet and --verbose")
+		}
+	}
+	if nonMatching && !verbose {
+		return e.fatal("--non-matching is only valid with --verbose")
+	}
+
+	root, repo, err := loadRepository(e)
+	if err != nil {
+		return e.fatal("%v", err)
+	}
+
+	checker := &ignoreChecker{
+		env:           e,
+		root:          root,
+		repo:          repo,
+		out:           bufio.NewWriter(e.stdout),
+		quiet:         quiet,
+		verbose:       verbose,
+		nonMatching:   nonMatching,
+		nulTerminated: nulTerminated,
+	}
+	defer checker.out.Flush()
+
+	if stdin {
+		err = checker.checkStdin()
+	} else {
+		for _, path := range paths {
+			if err = checker.check(path); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		checker.out.Flush()
+		return e.fatal("%v", err)
+	}
+
+	if checker.ignored == 0 {
+		return exitFalse
+	}
+	return exitOK
+}
+
+type ignoreChecker struct {
+	*env
+	root string
+	repo *goignore.Repository
+	out  *bufio.Writer
+
+	quiet         bool
+	verbose       bool
+	nonMatching   bool
+	nulTerminated bool
+
+	// the number of paths which matched a pattern
+	ignored int
+}
+
+type pathOutsideError struct {
+	path string
+	root string
+}
+
+func (e *pathOutsideError) Error() string {
+	return e.path + ": '" + e.path + "' is outside repository at '" + e.root + "'"
+}
+
+// Checks a path from the command line and prints it if it matches
+func (c *ignoreChecker) check(path string) error {
+	rel, ok := relativeToRoot(c.env, c.root, path)
+	if !ok {
+		return &pathOutsideError{path: path, root: c.root}
+	}
+
+	// like git, look at the file to find out whether it is a directory
+	isDir := strings.HasSuffix(path, "/")
+	if info, err := os.Lstat(filepath.Join(c.root, filepath.FromSlash(rel))); err == nil {
+		isDir = info.IsDir()
+	}
+	if isDir {
+		rel += "/"
+	}
+
+	// negated patterns are only shown with --verbose, and then they count as matches too
+	match := c.repo.Explain(rel)
+	if match != nil && match.Negate && !c.verbose {
+		match = nil
+	}
+
+	if !c.quiet && (match != nil || c.nonMatching) {
+		c.print(path, match)
+	}
+	if match != nil {
+		c.ignored++
+	}
+	return nil
+}
+
+func (c *ignoreChecker) print(path string, match *goignore.Match) {
+	if !c.nulTerminated {
+		path = quotePath(path)
+	}
+
+	if c.verbose {
+		if match == nil {
+			match = &goignore.Match{}
+		}
+		line := ""
+		if match.Line != 0 {
+			line = strconv.Itoa(match.Line)
+		}
+
+		if c.nulTerminated {
+			for _, field := range []string{match.Source, line, match.Pattern} {
+				c.out.WriteString(field)
+				c.out.WriteByte(0)
+			}
+		} else {
+			c.out.WriteString(match.Source + ":" + line + ":" + match.Pattern + "\t")
+		}
+	}
+
+	c.out.WriteString(path)
+	if c.nulTerminated {
+		c.out.WriteByte(0)
+	} else {
+		c.out.WriteByte('\n')
+	}
+}
+
+// Checks the paths read from stdin, one per line or NUL terminated with -z
+// The output is flushed after each path, so the command can be used interactively
+func (c *ignoreChecker) checkStdin() error {
+	scanner := bufio.NewScanner(c.stdin)
+	if c.nulTerminated {
+		scanner.Split(scanNul)
+	}
+
+	for scanner.Scan() {
+		path := scanner.Text()
+		if !c.nulTerminated {
+			path = strings.TrimSuffix(path, "\r")
+			if strings.HasPrefix(path, "\"") {
+				unquoted, err := unquotePath(path)
+				if err != nil {
+					return err
+				}
+				path = unquoted
+			}
+		}
+
+		if err := c.check(path); err != nil {
+			return err
+		}
+		if err := c.out.Flush(); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// A bufio.SplitFunc for NUL terminated records
+func scanNul(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The expected outputs are the ones of git check-ignore --no-index on the same tree
+func checkIgnoreTree(t *testing.T) string {
+	return writeTree(t, map[string]string{
+		".git/info/exclude": "*.ex\n",
+		".gitignore":        "*.o\n!keep.o\nbuild/\n",
+		"sub/.gitignore":    "x\n",
+		"sub/deep/":         "",
+		"build/":            "",
+	})
+}
+
+func TestCheckIgnore(t *testing.T) {
+	sub := filepath.Join(checkIgnoreTree(t), "sub")
+
+	code, stdout, stderr := runIn(sub, "", "check-ignore", "-v", "x", "a.o", "../keep.o", "../build/f", "deep/x", "c.ex", "tab\tx.o", "é.o", "none")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stderr)
+	assert.Equal(t, strings.Join([]string{
+		"sub/.gitignore:1:x\tx",
+		".gitignore:1:*.o\ta.o",
+		".gitignore:2:!keep.o\t../keep.o",
+		".gitignore:3:build/\t../build/f",
+		"sub/.gitignore:1:x\tdeep/x",
+		".git/info/exclude:1:*.ex\tc.ex",
+		".gitignore:1:*.o\t\"tab\\tx.o\"",
+		".gitignore:1:*.o\t\"\\303\\251.o\"",
+		"",
+	}, "\n"), stdout)
+
+	// negated matches are only shown with --verbose
+	code, stdout, _ = runIn(sub, "", "check-ignore", "keep.o", "a.o")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "a.o\n", stdout)
+
+	code, stdout, _ = runIn(sub, "", "check-ignore", "keep.o", "none")
+	assert.Equal(t, exitFalse, code)
+	assert.Empty(t, stdout)
+
+	code, stdout, _ = runIn(sub, "", "check-ignore", "-vn", "none", "a.o")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "::\tnone\n.gitignore:1:*.o\ta.o\n", stdout)
+
+	code, stdout, _ = runIn(sub, "", "check-ignore", "-q", "a.o")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+}
+
+func TestCheckIgnore_Directories(t *testing.T) {
+	root := checkIgnoreTree(t)
+
+	// build exists, so it is a directory even without a trailing slash
+	code, stdout, _ := runIn(root, "", "check-ignore", "build", "other/build", "other/build/")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "build\nother/build/\n", stdout)
+}
+
+func TestCheckIgnore_Stdin(t *testing.T) {
+	root := checkIgnoreTree(t)
+
+	code, stdout, _ := runIn(root, "a.o\r\nkeep.o\n\"q\\tx.o\"\n", "check-ignore", "--stdin")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "a.o\n\"q\\tx.o\"\n", stdout)
+
+	code, stdout, _ = runIn(root, "a.o\x00keep.o\x00none\x00q\tx.o", "check-ignore", "-vnz", "--stdin")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, ".gitignore\x001\x00*.o\x00a.o\x00"+
+		".gitignore\x002\x00!keep.o\x00keep.o\x00"+
+		"\x00\x00\x00none\x00"+
+		".gitignore\x001\x00*.o\x00q\tx.o\x00", stdout)
+
+	code, stdout, _ = runIn(root, "a.o\x00none\x00", "check-ignore", "-z", "--stdin")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "a.o\x00", stdout)
+
+	code, _, stderr := runIn(root, "\"bad\n", "check-ignore", "--stdin")
+	assert.Equal(t, exitFatal, code)
+	assert.Equal(t, "fatal: line is badly quoted\n", stderr)
+}
+
+func TestCheckIgnore_Errors(t *testing.T) {
+	root := checkIgnoreTree(t)
+
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--stdin", "a"}, "cannot specify pathnames with --stdin"},
+		{[]string{"-z", "a"}, "-z only makes sense with --stdin"},
+		{[]string{"-v"}, "no path specified"},
+		{[]string{"-q", "a", "b"}, "--quiet is only valid with a single pathname"},
+		{[]string{"-qv", "a"}, "cannot have both --quiet and --verbose"},
+		{[]string{"-n", "a"}, "--non-matching is only valid with --verbose"},
+		{[]string{"../x"}, "../x: '../x' is outside repository at '" + root + "'"},
+	} {
+		code, stdout, stderr := runIn(root, "", append([]string{"check-ignore"}, test.args...)...)
+		assert.Equal(t, exitFatal, code, test.args)
+		assert.Empty(t, stdout, test.args)
+		assert.Equal(t, "fatal: "+test.expected+"\n", stderr, test.args)
+	}
+
+	code, _, stderr := runIn(root, "", "check-ignore", "--bogus", "a")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, "error: unknown option `bogus'\n"+checkIgnoreUsage, stderr)
+
+	// like git, the usage is printed to stdout with -h
+	code, stdout, stderr := runIn(root, "", "check-ignore", "-h", "a")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, checkIgnoreUsage, stdout)
+	assert.Empty(t, stderr)
+
+	code, stdout, _ = runIn(root, "", "check-ignore", "--", "-h")
+	assert.Equal(t, exitFalse, code)
+	assert.Empty(t, stdout)
+}
+
+func TestCheckIgnore_NoRepository(t *testing.T) {
+	// without a .git directory, the working directory is the top of the tree
+	root := writeTree(t, map[string]string{
+		".gitignore":     "*.o\n",
+		"sub/.gitignore": "/x\n",
+	})
+
+	code, stdout, _ := runIn(root, "", "check-ignore", "-v", "a.o", "sub/x", "x")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, ".gitignore:1:*.o\ta.o\nsub/.gitignore:1:/x\tsub/x\n", stdout)
+}
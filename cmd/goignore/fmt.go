@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/botondmester/goignore"
+)
+
+const fmtUsage = `usage: goignore fmt [<options>] [<file>...]
+
+    -l                    list the files whose formatting differs
+    -w                    write the result to the files instead of printing it
+`
+
+// Formats ignore files like gofmt does for Go files, standard input is formatted without files
+func format(e *env, args []string) int {
+	var list, write bool
+	files, code, ok := e.parseCommandLine(fmtUsage, args, map[string]*bool{
+		"l": &list,
+		"w": &write,
+	})
+	if !ok {
+		return code
+	}
+
+	if len(files) == 0 {
+		if write {
+			return e.fatal("cannot use -w with standard input")
+		}
+		src, err := io.ReadAll(e.stdin)
+		if err != nil {
+			return e.fatal("%v", err)
+		}
+		if formatted := goignore.Format(src); list {
+			if !bytes.Equal(src, formatted) {
+				io.WriteString(e.stdout, "<standard input>\n")
+			}
+		} else {
+			e.stdout.Write(formatted)
+		}
+		return exitOK
+	}
+
+	for _, file := range files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(e.dir, path)
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return e.fatal("%v", err)
+		}
+
+		formatted := goignore.Format(src)
+		changed := !bytes.Equal(src, formatted)
+		if list && changed {
+			io.WriteString(e.stdout, file+"\n")
+		}
+		if write && changed {
+			if err := os.WriteFile(path, formatted, 0o644); err != nil {
+				return e.fatal("%v", err)
+			}
+		}
+		if !list && !write {
+			e.stdout.Write(formatted)
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFmt(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"messy":     "# comment\r\n**/*.o\r\nfoo  \r\n",
+		"sub/clean": "# comment\n*.o\n",
+	})
+
+	code, stdout, _ := runIn(root, "", "fmt", "messy", "sub/clean")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "# comment\n*.o\nfoo\n# comment\n*.o\n", stdout)
+
+	code, stdout, _ = runIn(root, "", "fmt", "-l", "messy", "sub/clean")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "messy\n", stdout)
+
+	code, stdout, _ = runIn(root, "", "fmt", "-lw", "messy")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "messy\n", stdout)
+	written, err := os.ReadFile(filepath.Join(root, "messy"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# comment\n*.o\nfoo\n", string(written))
+
+	_, stdout, _ = runIn(root, "/a//b\n\n\n", "fmt")
+	assert.Equal(t, "/a/b\n", stdout)
+
+	_, stdout, _ = runIn(root, "/a//b\n", "fmt", "-l")
+	assert.Equal(t, "<standard input>\n", stdout)
+
+	code, _, stderr := runIn(root, "", "fmt", "-w")
+	assert.Equal(t, exitFatal, code)
+	assert.Equal(t, "fatal: cannot use -w with standard input\n", stderr)
+
+	code, stdout, _ = runIn(root, "", "fmt", "-lh")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, fmtUsage, stdout)
+}
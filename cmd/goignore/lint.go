@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/botondmester/goignore"
+)
+
+const lintUsage = `usage: goignore lint [<options>] [<file>...]
+
+    --json                print the findings as a JSON array
+`
+
+// The JSON form of a goignore.Finding
+type lintFinding struct {
+	Check   goignore.LintCheck `json:"check"`
+	Source  string             `json:"source"`
+	Line    int                `json:"line"`
+	Column  int                `json:"column"`
+	Pattern string             `json:"pattern"`
+	Message string             `json:"message"`
+	Related *lintRelated       `json:"related,omitempty"`
+}
+
+type lintRelated struct {
+	Source  string `json:"source"`
+	Line    int    `json:"line"`
+	Pattern string `json:"pattern"`
+}
+
+// Lints the given ignore files, or every ignore file of the repository
+// The exit code is 1 if anything was found
+func lint(e *env, args []string) int {
+	var jsonOutput bool
+	files, code, ok := e.parseCommandLine(lintUsage, args, map[string]*bool{
+		"json": &jsonOutput,
+	})
+	if !ok {
+		return code
+	}
+
+	var findings []*goignore.Finding
+	if len(files) == 0 {
+		_, repo, err := loadRepository(e)
+		if err != nil {
+			return e.fatal("%v", err)
+		}
+		findings = repo.Lint()
+	}
+	for _, file := range files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(e.dir, path)
+		}
+		ignore, err := goignore.CompileIgnoreFile(path, goignore.WithSource(file))
+		if err != nil {
+			return e.fatal("%v", err)
+		}
+		findings = append(findings, ignore.Lint()...)
+	}
+
+	out := bufio.NewWriter(e.stdout)
+	defer out.Flush()
+
+	if jsonOutput {
+		output := make([]lintFinding, len(findings))
+		for i, finding := range findings {
+			output[i] = lintFinding{
+				Check:   finding.Check,
+				Source:  finding.Source,
+				Line:    finding.Line,
+				Column:  finding.Column,
+				Pattern: finding.Pattern,
+				Message: finding.Message,
+			}
+			if related := finding.Related; related != nil {
+				output[i].Related = &lintRelated{Source: related.Source, Line: related.Line, Pattern: related.Pattern}
+			}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			return e.fatal("%v", err)
+		}
+	} else {
+		for _, finding := range findings {
+			out.WriteString(finding.String() + "\n")
+		}
+	}
+
+	if len(findings) > 0 {
+		return exitFalse
+	}
+	return exitOK
+}
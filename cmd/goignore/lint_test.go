@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".git/HEAD":      "",
+		".gitignore":     "folder\n!folder/subfolder\n",
+		"sub/.gitignore": "a.o\n*.o\n",
+		"clean":          "*.o\n",
+	})
+
+	code, stdout, stderr := runIn(root, "", "lint")
+	assert.Equal(t, exitFalse, code)
+	assert.Empty(t, stderr)
+	assert.Equal(t, lines(
+		".gitignore:2:1: can't re-include anything, the parent directory folder/ is excluded by line 1 (folder): !folder/subfolder",
+		"sub/.gitignore:1:1: shadowed by line 2 (*.o): a.o",
+	), stdout)
+
+	code, stdout, _ = runIn(root, "", "lint", "clean")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+
+	code, stdout, _ = runIn(filepath.Join(root, "sub"), "", "lint", "--json", ".gitignore", "../clean")
+	assert.Equal(t, exitFalse, code)
+	assert.JSONEq(t, `[{
+		"check": "shadowed",
+		"source": ".gitignore",
+		"line": 1,
+		"column": 1,
+		"pattern": "a.o",
+		"message": "shadowed by line 2 (*.o)",
+		"related": {"source": ".gitignore", "line": 2, "pattern": "*.o"}
+	}]`, stdout)
+
+	_, stdout, _ = runIn(root, "", "lint", "--json", "clean")
+	assert.Equal(t, "[]\n", stdout)
+
+	code, _, stderr = runIn(root, "", "lint", "missing")
+	assert.Equal(t, exitFatal, code)
+	assert.Contains(t, stderr, "missing: no such file or directory")
+
+	code, stdout, _ = runIn(root, "", "lint", "--help")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, lintUsage, stdout)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/botondmester/goignore"
+)
+
+const lsUsage = `usage: goignore ls [<options>] [<path>...]
+
+    -i, --ignored         show only ignored files
+    -z                    separate paths with a NUL character
+    --directory           show ignored directories as "dir/" instead of their contents
+`
+
+// Lists the files which are not ignored, or only the ignored ones, like
+// `git ls-files --others --exclude-standard [--ignored]`
+// The index is never read, so tracked files are listed too
+func ls(e *env, args []string) int {
+	var ignored, nulTerminated, directory bool
+	paths, code, ok := e.parseCommandLine(lsUsage, args, map[string]*bool{
+		"i":         &ignored,
+		"ignored":   &ignored,
+		"z":         &nulTerminated,
+		"directory": &directory,
+	})
+	if !ok {
+		return code
+	}
+	if directory && !ignored {
+		// every directory is untracked without an index, git would only show the top level ones
+		return e.fatal("--directory only makes sense with --ignored")
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	root, repo, err := loadRepository(e)
+	if err != nil {
+		return e.fatal("%v", err)
+	}
+
+	lister := &fileLister{
+		env:           e,
+		root:          root,
+		repo:          repo,
+		out:           bufio.NewWriter(e.stdout),
+		ignored:       ignored,
+		directory:     directory,
+		nulTerminated: nulTerminated,
+	}
+	defer lister.out.Flush()
+
+	for _, path := range paths {
+		rel, ok := relativeToRoot(e, root, path)
+		if !ok {
+			lister.out.Flush()
+			return e.fatal("%s: '%s' is outside repository at '%s'", path, path, root)
+		}
+		if err := lister.list(rel); err != nil {
+			lister.out.Flush()
+			return e.fatal("%v", err)
+		}
+	}
+	return exitOK
+}
+
+type fileLister struct {
+	*env
+	root string
+	repo *goignore.Repository
+	out  *bufio.Writer
+
+	ignored       bool
+	directory     bool
+	nulTerminated bool
+}
+
+// Lists a path relative to the root, which may be a file or a directory
+func (l *fileLister) list(rel string) error {
+	info, err := os.Lstat(l.fsPath(rel))
+	if err != nil {
+		return err
+	}
+	isIgnored := rel != "." && l.repo.MatchesPathIsDir(rel, info.IsDir())
+
+	switch {
+	case !info.IsDir():
+		if isIgnored == l.ignored {
+			l.print(rel, false)
+		}
+		return nil
+	case !l.directory:
+		return l.walk(rel, isIgnored)
+	case isIgnored:
+		l.print(rel, true)
+		return nil
+	}
+
+	// the directories given on the command line are not collapsed, even if all their files are ignored
+	output, _, err := l.collapse(rel)
+	for _, entry := range output {
+		l.print(entry.rel, entry.isDir)
+	}
+	return err
+}
+
+// Prints the files below dir which are ignored, or not ignored, dirIgnored is set if dir is ignored itself
+func (l *fileLister) walk(dir string, dirIgnored bool) error {
+	entries, err := os.ReadDir(l.fsPath(dir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() == ".git" {
+			continue
+		}
+
+		// everything in an ignored directory is ignored, there's no need to match it
+		rel := path.Join(dir, entry.Name())
+		isIgnored := dirIgnored || l.repo.MatchesPathIsDir(rel, entry.IsDir())
+		switch {
+		case !entry.IsDir():
+			if isIgnored == l.ignored {
+				l.print(rel, false)
+			}
+		case !isIgnored || l.ignored:
+			if err := l.walk(rel, isIgnored); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type listedPath struct {
+	rel   string
+	isDir bool
+}
+
+// Collects the ignored paths below dir, which is not ignored, for --directory
+// Ignored directories are listed instead of their contents, and so are the ones which
+// only have ignored files in them, hasKept is set if dir has files which aren't ignored
+func (l *fileLister) collapse(dir string) (output []listedPath, hasKept bool, err error) {
+	entries, err := os.ReadDir(l.fsPath(dir))
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() == ".git" {
+			continue
+		}
+
+		rel := path.Join(dir, entry.Name())
+		isIgnored := l.repo.MatchesPathIsDir(rel, entry.IsDir())
+		switch {
+		case isIgnored:
+			output = append(output, listedPath{rel: rel, isDir: entry.IsDir()})
+		case !entry.IsDir():
+			hasKept = true
+		default:
+			subdirectoryOutput, subdirectoryHasKept, err := l.collapse(rel)
+			if err != nil {
+				return nil, false, err
+			}
+			if subdirectoryHasKept {
+				output = append(output, subdirectoryOutput...)
+				hasKept = true
+			} else if len(subdirectoryOutput) > 0 {
+				output = append(output, listedPath{rel: rel, isDir: true})
+			}
+		}
+	}
+	return output, hasKept, nil
+}
+
+// Prints a path relative to the working directory, like git does
+func (l *fileLister) print(rel string, isDir bool) {
+	name, err := filepath.Rel(l.dir, l.fsPath(rel))
+	if err != nil {
+		name = l.fsPath(rel)
+	}
+	name = filepath.ToSlash(name)
+	if isDir {
+		name += "/"
+	}
+
+	if l.nulTerminated {
+		l.out.WriteString(name)
+		l.out.WriteByte(0)
+	} else {
+		l.out.WriteString(quotePath(name))
+		l.out.WriteByte('\n')
+	}
+}
+
+func (l *fileLister) fsPath(rel string) string {
+	return filepath.Join(l.root, filepath.FromSlash(rel))
+}
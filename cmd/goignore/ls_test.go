@@ -0,0 +1,106 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lsTree(t *testing.T) string {
+	return writeTree(t, map[string]string{
+		".git/HEAD":         "",
+		".git/info/exclude": "*.ex\n",
+		".gitignore":        "*.o\nbuild/\n!keep.o\n",
+		"build/b":           "",
+		"build/x/a":         "",
+		"allign/a.o":        "",
+		"allign/b.o":        "",
+		"mixed/a.o":         "",
+		"mixed/c.c":         "",
+		"mixed/deep/d.o":    "",
+		"sub/.gitignore":    "/s.c\n",
+		"sub/s.c":           "",
+		"sub/t.c":           "",
+		"sub/keep.o":        "",
+		"empty/":            "",
+		"top.o":             "",
+		"top.ex":            "",
+		"top.c":             "",
+		"tab\tx.c":          "",
+	})
+}
+
+func lines(paths ...string) string {
+	return strings.Join(paths, "\n") + "\n"
+}
+
+func TestLs(t *testing.T) {
+	root := lsTree(t)
+
+	code, stdout, stderr := runIn(root, "", "ls")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stderr)
+	assert.Equal(t, lines(".gitignore", "mixed/c.c", "sub/.gitignore", "sub/keep.o", "sub/t.c", `"tab\tx.c"`, "top.c"), stdout)
+
+	_, stdout, _ = runIn(root, "", "ls", "--ignored")
+	assert.Equal(t, lines("allign/a.o", "allign/b.o", "build/b", "build/x/a", "mixed/a.o", "mixed/deep/d.o", "sub/s.c", "top.ex", "top.o"), stdout)
+
+	_, stdout, _ = runIn(root, "", "ls", "-z")
+	assert.Equal(t, ".gitignore\x00mixed/c.c\x00sub/.gitignore\x00sub/keep.o\x00sub/t.c\x00tab\tx.c\x00top.c\x00", stdout)
+}
+
+func TestLs_Directory(t *testing.T) {
+	root := lsTree(t)
+
+	_, stdout, _ := runIn(root, "", "ls", "-i", "--directory")
+	assert.Equal(t, lines("allign/", "build/", "mixed/a.o", "mixed/deep/", "sub/s.c", "top.ex", "top.o"), stdout)
+
+	// the directories on the command line are not collapsed, unless they are ignored
+	_, stdout, _ = runIn(root, "", "ls", "-i", "--directory", "allign", "build")
+	assert.Equal(t, lines("allign/a.o", "allign/b.o", "build/"), stdout)
+
+	code, _, stderr := runIn(root, "", "ls", "--directory")
+	assert.Equal(t, exitFatal, code)
+	assert.Equal(t, "fatal: --directory only makes sense with --ignored\n", stderr)
+}
+
+func TestLs_Paths(t *testing.T) {
+	root := lsTree(t)
+	sub := filepath.Join(root, "sub")
+
+	// paths are relative to the working directory, and only the ones below it are listed
+	_, stdout, _ := runIn(sub, "", "ls")
+	assert.Equal(t, lines(".gitignore", "keep.o", "t.c"), stdout)
+
+	_, stdout, _ = runIn(sub, "", "ls", "../mixed", "../top.c", "../top.o", "../build")
+	assert.Equal(t, lines("../mixed/c.c", "../top.c"), stdout)
+
+	_, stdout, _ = runIn(sub, "", "ls", "-i", "../build/x", "s.c", "t.c")
+	assert.Equal(t, lines("../build/x/a", "s.c"), stdout)
+
+	code, _, stderr := runIn(sub, "", "ls", "missing")
+	assert.Equal(t, exitFatal, code)
+	assert.Contains(t, stderr, "fatal: lstat ")
+
+	code, _, stderr = runIn(root, "", "ls", "..")
+	assert.Equal(t, exitFatal, code)
+	assert.Equal(t, "fatal: ..: '..' is outside repository at '"+root+"'\n", stderr)
+}
+
+func TestLs_Help(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".git/HEAD": "",
+		"-h":        "",
+	})
+
+	code, stdout, _ := runIn(root, "", "ls", "-h")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, lsUsage, stdout)
+
+	// after "--" it is a path
+	code, stdout, _ = runIn(root, "", "ls", "--", "-h")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, lines("-h"), stdout)
+}
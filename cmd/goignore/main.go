@@ -0,0 +1,196 @@
+// Command goignore works with .gitignore files without needing git
+//
+// Usage:
+//
+//	goignore check-ignore [<options>] <pathname>...
+//	goignore check-ignore [<options>] --stdin
+//	goignore ls [<options>] [<path>...]
+//	goignore lint [--json] [<file>...]
+//	goignore fmt [-l] [-w] [<file>...]
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/botondmester/goignore"
+)
+
+// Exit codes, the same ones git uses
+const (
+	exitOK    = 0
+	exitFalse = 1   // e.g. no path was ignored
+	exitFatal = 128 // the command failed
+	exitUsage = 129 // the command line was wrong
+)
+
+// The environment a command runs in, so commands can be tested without starting a process
+type env struct {
+	dir    string // the working directory, paths on the command line are relative to it
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Prints an error like git does, and returns the exit code for it
+func (e *env) fatal(format string, args ...any) int {
+	fmt.Fprintf(e.stderr, "fatal: "+format+"\n", args...)
+	return exitFatal
+}
+
+// Prints an error about the command line, followed by the usage of the command
+func (e *env) usageError(usage string, format string, args ...any) int {
+	fmt.Fprintf(e.stderr, "error: "+format+"\n", args...)
+	fmt.Fprint(e.stderr, usage)
+	return exitUsage
+}
+
+type command struct {
+	name string
+	run  func(e *env, args []string) int
+}
+
+var commands = []command{
+	{"check-ignore", checkIgnore},
+	{"ls", ls},
+	{"lint", lint},
+	{"fmt", format},
+}
+
+const usage = `usage: goignore <command> [<args>]
+
+commands:
+    check-ignore    debug gitignore / exclude files, like git check-ignore
+    ls              list the files which are not ignored, or the ignored ones
+    lint            find rules which don't do what they seem to
+    fmt             rewrite ignore files into a canonical form
+`
+
+func main() {
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fatal:", err)
+		os.Exit(exitFatal)
+	}
+
+	os.Exit(run(&env{
+		dir:    dir,
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}, os.Args[1:]))
+}
+
+func run(e *env, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(e.stderr, usage)
+		return exitUsage
+	}
+
+	for _, command := range commands {
+		if command.name == args[0] {
+			return command.run(e, args[1:])
+		}
+	}
+
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(e.stdout, usage)
+		return exitOK
+	}
+	return e.usageError(usage, "unknown command '%s'", args[0])
+}
+
+// Returned by parseOptions for -h and --help
+var errHelp = errors.New("help requested")
+
+// Parses the options of a command, ok is false if the command has to exit with code then
+// Like in git, -h and --help print the usage to stdout, and unknown options print it to stderr
+func (e *env) parseCommandLine(usage string, args []string, flags map[string]*bool) (operands []string, code int, ok bool) {
+	operands, err := parseOptions(args, flags)
+	switch {
+	case err == errHelp:
+		fmt.Fprint(e.stdout, usage)
+		return nil, exitUsage, false
+	case err != nil:
+		return nil, e.usageError(usage, "%v", err), false
+	}
+	return operands, exitOK, true
+}
+
+// Parses options like git does: "-vn" is the same as "-v -n", and "--" ends the options
+// flags maps each spelling of an option, e.g. "v" and "verbose", to the value it sets
+// The error is errHelp for -h and --help, unless flags has them
+func parseOptions(args []string, flags map[string]*bool) (operands []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(operands, args[i+1:]...), nil
+		case arg == "--help" && flags["help"] == nil:
+			return nil, errHelp
+		case strings.HasPrefix(arg, "--"):
+			flag, ok := flags[arg[2:]]
+			if !ok || len(arg) == 3 {
+				return nil, fmt.Errorf("unknown option `%s'", arg[2:])
+			}
+			*flag = true
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			for _, short := range arg[1:] {
+				flag, ok := flags[string(short)]
+				if !ok && short == 'h' {
+					return nil, errHelp
+				}
+				if !ok {
+					return nil, fmt.Errorf("unknown switch `%c'", short)
+				}
+				*flag = true
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return operands, nil
+}
+
+// Finds the top of the repository containing dir, the directory with a .git entry
+func findRepositoryRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Loads the ignore rules of the repository containing the working directory, like git does
+// Outside of a repository, only the .gitignore files below the working directory are used
+func loadRepository(e *env) (root string, repo *goignore.Repository, err error) {
+	if root, ok := findRepositoryRoot(e.dir); ok {
+		repo, err = goignore.CompileRepositoryStandard(root)
+		return root, repo, err
+	}
+	repo, err = goignore.CompileRepository(e.dir)
+	return e.dir, repo, err
+}
+
+// Converts a path on the command line to a slash separated path relative to root
+// ok is false if the path is outside of root
+func relativeToRoot(e *env, root string, path string) (rel string, ok bool) {
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(e.dir, abs)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || (rel != "." && !filepath.IsLocal(rel)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates the files in a temporary directory, directories end with a slash
+// HOME points to an empty directory too, so the git config of the user running the tests is not read
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			assert.NoError(t, os.MkdirAll(path, 0o755))
+			continue
+		}
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return root
+}
+
+// Runs the command in dir, and returns its exit code and output
+func runIn(dir string, stdin string, args ...string) (code int, stdout string, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(&env{
+		dir:    dir,
+		stdin:  strings.NewReader(stdin),
+		stdout: &out,
+		stderr: &errOut,
+	}, args)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	code, _, stderr := runIn(t.TempDir(), "")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, usage, stderr)
+
+	code, stdout, _ := runIn(t.TempDir(), "", "--help")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, usage, stdout)
+
+	code, _, stderr = runIn(t.TempDir(), "", "bogus")
+	assert.Equal(t, exitUsage, code)
+	assert.True(t, strings.HasPrefix(stderr, "error: unknown command 'bogus'\n"))
+}
+
+func TestParseOptions(t *testing.T) {
+	var a, b, long bool
+	flags := map[string]*bool{"a": &a, "b": &b, "long": &long}
+
+	operands, err := parseOptions([]string{"x", "-ab", "--long", "-", "--", "-a"}, flags)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "-", "-a"}, operands)
+	assert.True(t, a && b && long)
+
+	_, err = parseOptions([]string{"--bogus"}, flags)
+	assert.EqualError(t, err, "unknown option `bogus'")
+	_, err = parseOptions([]string{"-ac"}, flags)
+	assert.EqualError(t, err, "unknown switch `c'")
+
+	for _, args := range [][]string{{"-h"}, {"--help"}, {"x", "-ah"}} {
+		_, err = parseOptions(args, flags)
+		assert.Equal(t, errHelp, err, args)
+	}
+	operands, err = parseOptions([]string{"--", "-h", "--help"}, flags)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-h", "--help"}, operands)
+}
+
+func TestRelativeToRoot(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	e := &env{dir: filepath.Join(root, "sub")}
+
+	for path, expected := range map[string]string{
+		"a":                                "sub/a",
+		"../a":                             "a",
+		"..":                               ".",
+		"./x/../y":                         "sub/y",
+		filepath.Join(root, "abs", "path"): "abs/path",
+	} {
+		rel, ok := relativeToRoot(e, root, path)
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, rel, path)
+	}
+
+	for _, path := range []string{"../..", "../../repo2/a", filepath.FromSlash("/elsewhere")} {
+		_, ok := relativeToRoot(e, root, path)
+		assert.False(t, ok, path)
+	}
+}
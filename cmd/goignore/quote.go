@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// The escapes git uses for quoting paths, besides octal ones
+var quoteEscapes = map[byte]byte{
+	'\a': 'a',
+	'\b': 'b',
+	'\t': 't',
+	'\n': 'n',
+	'\v': 'v',
+	'\f': 'f',
+	'\r': 'r',
+	'"':  '"',
+	'\\': '\\',
+}
+
+func needsQuoting(c byte) bool {
+	return c < 0x20 || c == '"' || c == '\\' || c >= 0x7f
+}
+
+// Quotes a path the way git prints it with core.quotePath=true, paths without special bytes are left alone
+func quotePath(path string) string {
+	i := 0
+	for i < len(path) && !needsQuoting(path[i]) {
+		i++
+	}
+	if i == len(path) {
+		return path
+	}
+
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for j := 0; j < len(path); j++ {
+		c := path[j]
+		if !needsQuoting(c) {
+			quoted.WriteByte(c)
+			continue
+		}
+		quoted.WriteByte('\\')
+		if escape, ok := quoteEscapes[c]; ok {
+			quoted.WriteByte(escape)
+			continue
+		}
+		quoted.WriteByte('0' + c>>6)
+		quoted.WriteByte('0' + c>>3&7)
+		quoted.WriteByte('0' + c&7)
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+var errBadlyQuoted = errors.New("line is badly quoted")
+
+// Reverses quotePath, for paths read from stdin which start with '"'
+func unquotePath(quoted string) (string, error) {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", errBadlyQuoted
+	}
+	quoted = quoted[1 : len(quoted)-1]
+
+	var path strings.Builder
+	for i := 0; i < len(quoted); i++ {
+		c := quoted[i]
+		if c == '"' {
+			return "", errBadlyQuoted
+		}
+		if c != '\\' {
+			path.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(quoted) {
+			return "", errBadlyQuoted
+		}
+		c = quoted[i]
+
+		if '0' <= c && c <= '3' {
+			if i+2 >= len(quoted) || !isOctal(quoted[i+1]) || !isOctal(quoted[i+2]) {
+				return "", errBadlyQuoted
+			}
+			path.WriteByte((c-'0')<<6 | (quoted[i+1]-'0')<<3 | (quoted[i+2] - '0'))
+			i += 2
+			continue
+		}
+
+		unescaped, ok := byte(0), false
+		for raw, escape := range quoteEscapes {
+			if escape == c {
+				unescaped, ok = raw, true
+				break
+			}
+		}
+		if !ok {
+			return "", errBadlyQuoted
+		}
+		path.WriteByte(unescaped)
+	}
+	return path.String(), nil
+}
+
+func isOctal(c byte) bool {
+	return '0' <= c && c <= '7'
+}
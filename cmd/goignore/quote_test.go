@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotePath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"":               "",
+		"plain/path.txt": "plain/path.txt",
+		"with space":     "with space",
+		"tab\tx":         `"tab\tx"`,
+		"new\nline":      `"new\nline"`,
+		`quote"d`:        `"quote\"d"`,
+		`back\slash`:     `"back\\slash"`,
+		"é":              `"\303\251"`,
+		"\x01\x7f":       `"\001\177"`,
+	} {
+		assert.Equal(t, expected, quotePath(path), path)
+
+		if expected != path {
+			unquoted, err := unquotePath(expected)
+			assert.NoError(t, err, expected)
+			assert.Equal(t, path, unquoted, expected)
+		}
+	}
+
+	for _, quoted := range []string{`"`, `"unterminated`, `"a"b"`, `"trailing\"`, `"\x"`, `"\40"`, `"\400"`} {
+		_, err := unquotePath(quoted)
+		assert.ErrorIs(t, err, errBadlyQuoted, quoted)
+	}
+}
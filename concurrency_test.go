@@ -0,0 +1,168 @@
+package goignore
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The paths each goroutine matches, a mix of ignored, re-included and unmatched paths in shared directories
+var concurrencyPaths = append(benchmarkListing()[:500],
+	"node_modules/react/index.js",
+	"web/.vscode/settings.json",
+	"web/.vscode/launch.json",
+	"api/__pycache__/x.pyc",
+	"build/out.o",
+	"src/build/out.o",
+	".env.example",
+	".env.local",
+	"Web/Node_Modules/x",
+)
+
+// Matches every path from many goroutines at once, and checks the answers against matching them one by one
+// Run with -race to catch unsynchronized access
+func hammer(t *testing.T, match func(path string) bool) {
+	expected := make([]bool, len(concurrencyPaths))
+	for i, path := range concurrencyPaths {
+		expected[i] = match(path)
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4*runtime.GOMAXPROCS(0); worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for round := 0; round < 3; round++ {
+				// every goroutine starts at a different path, so they don't move in lockstep
+				for j := range concurrencyPaths {
+					i := (j + worker*37) % len(concurrencyPaths)
+					if match(concurrencyPaths[i]) != expected[i] {
+						t.Errorf("%q gave a different result when matched concurrently", concurrencyPaths[i])
+						return
+					}
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentMatching(t *testing.T) {
+	lines := strings.Split(realisticIgnoreFile, "\n")
+
+	for _, test := range []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"index", []Option{WithIndex()}},
+		{"dir cache", []Option{WithDirCache(64)}},
+		{"small dir cache", []Option{WithDirCache(2)}},
+		{"case fold", []Option{WithCaseFold(), WithDirCache(64)}},
+		{"everything", []Option{WithIndex(), WithDirCache(64), WithCaseFold(), WithBaseDir("src")}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ignoreObject, err := Compile(lines, test.opts...)
+			assert.NoError(t, err)
+
+			hammer(t, ignoreObject.MatchesPath)
+			hammer(t, func(path string) bool {
+				return ignoreObject.MatchesCleanPath(path, false)
+			})
+			hammer(t, func(path string) bool {
+				return ignoreObject.Check(path) == Ignored
+			})
+			hammer(t, func(path string) bool {
+				return ignoreObject.Explain(path) != nil
+			})
+			hammer(t, func(path string) bool {
+				return ignoreObject.MatchPaths([]string{path})[0]
+			})
+		})
+	}
+}
+
+func TestConcurrentMatching_ClearCache(t *testing.T) {
+	ignoreObject, err := Compile(strings.Split(realisticIgnoreFile, "\n"), WithDirCache(64))
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ignoreObject.ClearCache()
+			runtime.Gosched()
+		}
+	}()
+
+	hammer(t, ignoreObject.MatchesPath)
+	<-done
+}
+
+func TestConcurrentMatching_Stack(t *testing.T) {
+	folded, err := Compile([]string{"node_modules/"}, WithCaseFold(), WithDirCache(16))
+	assert.NoError(t, err)
+	stack := NewStack(
+		folded,
+		CompileIgnoreLines(strings.Split(realisticIgnoreFile, "\n")...),
+	)
+
+	hammer(t, stack.MatchesPath)
+	hammer(t, func(path string) bool {
+		return stack.Check(path) == Ignored
+	})
+}
+
+func TestConcurrentMatching_Repository(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".gitignore":     realisticIgnoreFile,
+		"web/.gitignore": "!.vscode/launch.json\n",
+		"src/.gitignore": "!build/\n",
+	})
+
+	repo, err := CompileRepository(root, WithIndex())
+	assert.NoError(t, err)
+
+	hammer(t, repo.MatchesPath)
+	hammer(t, func(path string) bool {
+		return repo.Explain(path) != nil
+	})
+}
+
+// Matching from every core at once, like a parallel directory walker does
+func BenchmarkParallelMatching(b *testing.B) {
+	lines := strings.Split(realisticIgnoreFile, "\n")
+
+	for _, test := range []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"index", []Option{WithIndex()}},
+		{"dir cache", []Option{WithDirCache(4096)}},
+	} {
+		ignoreObject, _ := Compile(lines, test.opts...)
+
+		b.Run(test.name+"/MatchesPath", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					ignoreObject.MatchesPath(concurrencyPaths[i%len(concurrencyPaths)])
+					i++
+				}
+			})
+		})
+		b.Run(test.name+"/MatchesCleanPath", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					ignoreObject.MatchesCleanPath(concurrencyPaths[i%len(concurrencyPaths)], false)
+					i++
+				}
+			})
+		})
+	}
+}
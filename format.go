@@ -0,0 +1,214 @@
+package goignore
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Rewrites the contents of an ignore file into a canonical form:
+// unneeded backslashes and unescaped trailing spaces are removed, a "**/" in front of a single name is dropped,
+// repeated slashes and "**" components are collapsed, and lines end with "\n"
+// Comments and blank lines are kept, but blank lines at the end are removed
+// Every rewritten rule matches the same paths as the original one, with or without WithCaseFold(),
+// lines which can't be rewritten safely, like malformed patterns, are only trimmed
+func Format(src []byte) []byte {
+	src = bytes.TrimPrefix(src, []byte("\xef\xbb\xbf"))
+
+	var out bytes.Buffer
+	out.Grow(len(src))
+	blankLines := 0
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		line = FormatLine(line)
+		if line == "" {
+			// only written once something follows them
+			blankLines++
+			continue
+		}
+
+		for ; blankLines > 0; blankLines-- {
+			out.WriteByte('\n')
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// Same as Format, for a single line without the line ending
+func FormatLine(line string) string {
+	if strings.HasPrefix(line, "#") {
+		return line
+	}
+	if strings.IndexByte(line, 0) != -1 {
+		// everything after a NUL byte is cut off, it is easier to leave the line alone
+		return line
+	}
+
+	pattern := trimUnescapedTrailingSpaces(line)
+	if pattern == "" || pattern == "!" || pattern == "/" {
+		return pattern
+	}
+
+	canonical, ok := canonicalPattern(pattern)
+	if !ok || !equivalentPatterns(pattern, canonical) {
+		return pattern
+	}
+	return canonical
+}
+
+// Rewrites a pattern without trailing spaces, ok is false if it is malformed
+func canonicalPattern(pattern string) (canonical string, ok bool) {
+	negation := ""
+	if pattern[0] == '!' {
+		negation = "!"
+		pattern = pattern[1:]
+	}
+	anchor := ""
+	if pattern != "" && pattern[0] == '/' {
+		anchor = "/"
+		pattern = pattern[1:]
+	}
+	onlyDirectory := strings.HasSuffix(pattern, "/")
+
+	var components []string
+	for _, component := range mySplit(pattern, '/') {
+		component, ok = canonicalComponent(component)
+		if !ok {
+			return "", false
+		}
+		if component == "**" && len(components) > 0 && components[len(components)-1] == "**" {
+			continue
+		}
+		components = append(components, component)
+	}
+	if len(components) == 0 {
+		return "", false
+	}
+
+	// "**/foo" and "/**/foo" are the same as "foo", but "**/foo/" isn't the same as "foo/", see equivalentRules()
+	if len(components) == 2 && components[0] == "**" && components[1] != "**" && !onlyDirectory {
+		components = components[1:]
+		anchor = ""
+	}
+
+	canonical = strings.Join(components, "/")
+	if onlyDirectory {
+		canonical += "/"
+	}
+	if anchor == "" && negation == "" && (canonical[0] == '#' || canonical[0] == '!') {
+		// it would be a comment or a negation otherwise
+		canonical = "\\" + canonical
+	}
+	if strings.HasSuffix(canonical, " ") {
+		// only the last trailing space has to be escaped to keep all of them
+		canonical = canonical[:len(canonical)-1] + "\\ "
+	}
+	return negation + anchor + canonical, true
+}
+
+// Removes the backslashes which don't change the meaning of a component
+// Character classes are kept as they are, ok is false if the component is malformed
+func canonicalComponent(component string) (canonical string, ok bool) {
+	var out strings.Builder
+	for i := 0; i < len(component); {
+		switch c := component[i]; c {
+		case '\\':
+			if i+1 >= len(component) {
+				return "", false
+			}
+			// escaped uppercase letters never match with WithCaseFold(), so they have to stay escaped
+			if next := component[i+1]; strings.IndexByte("*?[\\", next) != -1 || ('A' <= next && next <= 'Z') {
+				out.WriteByte('\\')
+				out.WriteByte(next)
+			} else {
+				out.WriteByte(next)
+			}
+			i += 2
+		case '[':
+			end := classEnd(component, i)
+			if end == -1 {
+				return "", false
+			}
+			out.WriteString(component[i:end])
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String(), true
+}
+
+// Returns the index after the character class starting at start, or -1 if it is unclosed
+// It finds the end the same way makeRuleComponent() does
+func classEnd(component string, start int) int {
+	r := start + 1
+	if r < len(component) && (component[r] == '!' || component[r] == '^') {
+		r++
+	}
+	if r < len(component) && component[r] == ']' {
+		r++
+	}
+
+	for r < len(component) && component[r] != ']' {
+		switch {
+		case component[r] == '\\' && r+1 < len(component):
+			r += 2
+		case r+2 < len(component) && component[r] == '[' && component[r+1] == ':':
+			s := r + 2
+			for s < len(component) && (component[s] != ']' || component[s-1] != ':') {
+				s++
+			}
+			if s >= len(component) || s < r+4 {
+				return -1
+			}
+			r = s + 1
+		case r+2 < len(component) && component[r+1] == '-' && component[r+2] != ']':
+			r += 3
+		default:
+			r++
+		}
+	}
+
+	if r >= len(component) {
+		return -1
+	}
+	return r + 1
+}
+
+// Reports whether the two patterns match the same paths, with and without case folding
+func equivalentPatterns(a string, b string) bool {
+	for _, caseFold := range []bool{false, true} {
+		ruleA, errsA := createRule(a, caseFold)
+		ruleB, errsB := createRule(b, caseFold)
+		if len(errsA) > 0 || len(errsB) > 0 || !equivalentRules(&ruleA, &ruleB) {
+			return false
+		}
+	}
+	return true
+}
+
+// Same as sameRule(), but knows about the "**" components which make no difference
+// A leading "**" before a single component finds the last match in the path instead of the first one,
+// which only matters for directory only rules, since the parent directories are checked first otherwise
+func equivalentRules(a *rule, b *rule) bool {
+	normalizedA, normalizedB := normalizeStarstar(*a), normalizeStarstar(*b)
+	return sameRule(&normalizedA, &normalizedB)
+}
+
+func normalizeStarstar(r rule) rule {
+	components := make([]ruleComponent, 0, len(r.Components))
+	for _, component := range r.Components {
+		if component.Starstar && len(components) > 0 && components[len(components)-1].Starstar {
+			continue
+		}
+		components = append(components, component)
+	}
+	if len(components) == 2 && components[0].Starstar && !components[1].Starstar && !r.OnlyDirectory {
+		components = components[1:]
+		r.Relative = false
+	}
+	r.Components = components
+	return r
+}
@@ -0,0 +1,98 @@
+package goignore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLine(t *testing.T) {
+	for line, expected := range map[string]string{
+		"*.o":                "*.o",
+		"\\f\\o\\o":          "foo",
+		"foo  ":              "foo",
+		"a\\ b\\ \\ ":        "a b \\ ",
+		"\\ ":                "\\ ",
+		"\\#x":               "\\#x",
+		"\\!x":               "\\!x",
+		"!\\!x":              "!!x",
+		"**/foo":             "foo",
+		"/**/foo":            "foo",
+		"**/**/x":            "x",
+		"a/**/**/b":          "a/**/b",
+		"a//b//":             "a/b/",
+		"//a":                "/a",
+		"[a\\]]\\x":          "[a\\]]x",
+		"[[:alpha:]]\\.c":    "[[:alpha:]].c",
+		"\\*\\?\\[\\\\":      "\\*\\?\\[\\\\",
+		"# keep  \\ comment": "# keep  \\ comment",
+		"   ":                "",
+
+		// these would match different paths
+		"**/foo/": "**/foo/",
+		"\\A":     "\\A",
+
+		// malformed patterns are left alone
+		"[abc":  "[abc",
+		"x\\":   "x\\",
+		"a\\/b": "a\\/b",
+		"!/":    "!/",
+	} {
+		assert.Equal(t, expected, FormatLine(line), line)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	src := "\xef\xbb\xbf# build output  \r\n" +
+		"**/build/\r\n" +
+		"**/*.o\r\n" +
+		"\r\n" +
+		"\r\n" +
+		"# docs\n" +
+		"/docs//\\_site\n" +
+		"   \n" +
+		"\n"
+
+	assert.Equal(t, "# build output  \n"+
+		"**/build/\n"+
+		"*.o\n"+
+		"\n"+
+		"\n"+
+		"# docs\n"+
+		"/docs/_site\n", string(Format([]byte(src))))
+
+	assert.Equal(t, "", string(Format(nil)))
+	assert.Equal(t, "\nx\n", string(Format([]byte("\n\\x"))))
+}
+
+// Paths which exercise the rewrites made by FormatLine()
+var formatTestPaths = []string{
+	"foo", "foo/", "a/foo", "a/foo/", "foo/x/foo", "foo/x/foo/", "a/b", "a/x/b", "a/x/y/b", "#x", "!x", "x ", "x  ", "a b  ",
+	"A", "a", "FOO", "Foo/bar", "x.c", "_site", "docs/_site", "docs/_site/", "d/docs/_site", "[a]", "\\", "*", "a.o", "a/b/c.o",
+}
+
+func FuzzFormat(f *testing.F) {
+	for line := range map[string]bool{"**/foo": true, "a/**/**/b": true, "\\A": true, "a\\ b\\ \\ ": true, "[[:upper:]]\\.c": true} {
+		f.Add(line, "foo/x/foo")
+	}
+	f.Fuzz(func(t *testing.T, line string, path string) {
+		formatted := FormatLine(line)
+		if strings.ContainsAny(formatted, "\r\n") {
+			return
+		}
+
+		for _, caseFold := range []bool{false, true} {
+			var opts []Option
+			if caseFold {
+				opts = append(opts, WithCaseFold())
+			}
+			original, _ := Compile([]string{"*", "!*/", line}, opts...)
+			rewritten, _ := Compile([]string{"*", "!*/", formatted}, opts...)
+
+			for _, path := range append(formatTestPaths, path) {
+				assert.Equal(t, original.MatchesPath(path), rewritten.MatchesPath(path), "%q formatted to %q, for %q", line, formatted, path)
+			}
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package goignore
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The values read from git config files, keyed by "section.key" or "section.subsection.key"
+// Section and key names are lowercase, since they are case-insensitive in git
+// Later files override earlier ones, like in git
+type gitConfig map[string]string
+
+// Include directives are followed up to this depth, git uses the same limit
+const maxConfigIncludeDepth = 10
+
+// Reads the config files in the order git does, missing files are skipped
+func readGitConfigFiles(filenames ...string) (gitConfig, error) {
+	config := gitConfig{}
+	for _, filename := range filenames {
+		if filename == "" {
+			continue
+		}
+		if err := config.readFile(filename, 0); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// Parses a git config file into config, following [include] directives
+func (config gitConfig) readFile(filename string, depth int) error {
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	section := ""
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(strings.TrimSuffix(lines[i], "\r"))
+
+		if line != "" && line[0] == '[' {
+			// a key can follow the header on the same line, like in "[core] bare = true"
+			section, line = parseConfigSection(line)
+			line = strings.TrimSpace(line)
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		key, value := line, "true" // a key without a value is a boolean true
+		if eq := strings.IndexByte(line, '='); eq != -1 {
+			key = strings.TrimSpace(line[:eq])
+			raw := line[eq+1:]
+
+			// values can continue on the next line after a trailing backslash, but comments can't
+			for configValueContinues(raw) && i+1 < len(lines) {
+				i++
+				raw = raw[:len(raw)-1] + strings.TrimSuffix(lines[i], "\r")
+			}
+			value = parseConfigValue(raw)
+		}
+		key = section + "." + strings.ToLower(key)
+		config[key] = value
+
+		if key == "include.path" && depth < maxConfigIncludeDepth {
+			path := expandHome(value)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(filename), path)
+			}
+			if err := config.readFile(path, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the name of the section in a header like `[core]`, `[remote "origin"]` or `[remote.origin]`,
+// and the rest of the line after the header
+func parseConfigSection(line string) (section string, rest string) {
+	end := len(line)
+	inQuotes := false
+	for i := 1; i < len(line); i++ {
+		if c := line[i]; c == '"' {
+			inQuotes = !inQuotes
+		} else if c == '\\' && inQuotes {
+			i++
+		} else if c == ']' && !inQuotes {
+			end = i
+			break
+		}
+	}
+	header := strings.TrimSpace(line[1:end])
+	if end < len(line) {
+		rest = line[end+1:]
+	}
+
+	if quote := strings.IndexByte(header, '"'); quote != -1 {
+		name := strings.ToLower(strings.TrimSpace(header[:quote]))
+		subsection := strings.TrimSuffix(header[quote+1:], "\"")
+		subsection = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(subsection)
+		return name + "." + subsection, rest
+	}
+
+	return strings.ToLower(header), rest
+}
+
+// Tells whether a raw value continues on the next line, because it ends with a backslash
+// which isn't escaped and isn't in a comment, like git only continues values
+func configValueContinues(s string) bool {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && (c == '#' || c == ';'):
+			return false
+		case c == '\\':
+			if i+1 == len(s) {
+				return true
+			}
+			i++
+		}
+	}
+	return false
+}
+
+// Removes quotes, escapes and comments from a config value
+func parseConfigValue(s string) string {
+	var value strings.Builder
+	inQuotes := false
+	pendingSpace := 0 // spaces are only kept if something follows them
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+			continue
+		case !inQuotes && (c == '#' || c == ';'):
+			return value.String()
+		case !inQuotes && (c == ' ' || c == '\t'):
+			if value.Len() > 0 {
+				pendingSpace++
+			}
+			continue
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			default:
+				c = s[i]
+			}
+		}
+
+		for ; pendingSpace > 0; pendingSpace-- {
+			value.WriteByte(' ')
+		}
+		value.WriteByte(c)
+	}
+
+	return value.String()
+}
+
+// Interprets a boolean config value, invalid values are treated as false
+func parseConfigBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true
+	default:
+		return false
+	}
+}
+
+// Expands a leading "~/" to the home directory, like git does for paths in config files
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// Returns $XDG_CONFIG_HOME/git/<name>, or ~/.config/git/<name> if XDG_CONFIG_HOME is not set
+func xdgConfigPath(name string) string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", name)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", name)
+}
+
+// Returns the global config files in the order git reads them
+func globalGitConfigFiles() []string {
+	if global := os.Getenv("GIT_CONFIG_GLOBAL"); global != "" {
+		return []string{global}
+	}
+
+	files := []string{xdgConfigPath("config")}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".gitconfig"))
+	}
+	return files
+}
+
+// Finds the git directory of the repository at root, following a ".git" file like the ones in worktrees and submodules
+func findGitDir(root string) (string, error) {
+	gitDir := filepath.Join(root, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return gitDir, nil
+	}
+
+	content, err := os.ReadFile(gitDir)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(strings.SplitN(string(content), "\n", 2)[0])
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", errors.New("invalid gitfile format: " + gitDir)
+	}
+
+	path := strings.TrimSpace(line[len("gitdir:"):])
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	return path, nil
+}
+
+// Returns the directory which holds the config and info/exclude files shared by all worktrees
+func findCommonDir(gitDir string) string {
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	path := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(gitDir, path)
+	}
+	return path
+}
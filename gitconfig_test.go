@@ -0,0 +1,164 @@
+package goignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfigValue(t *testing.T) {
+	type TestCase struct {
+		input    string
+		expected string
+	}
+
+	tests := []TestCase{
+		{"", ""},
+		{" value ", "value"},
+		{" two  words ", "two  words"},
+		{` "quoted  value" `, "quoted  value"},
+		{`a"b c"d`, "ab cd"},
+		{"value # comment", "value"},
+		{"value ; comment", "value"},
+		{`"value # not a comment"`, "value # not a comment"},
+		{`C:\\path\\to`, `C:\path\to`},
+		{`tab\there`, "tab\there"},
+		{`\"`, `"`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, parseConfigValue(test.input))
+	}
+}
+
+func TestParseConfigSection(t *testing.T) {
+	type TestCase struct {
+		input    string
+		expected string
+		rest     string
+	}
+
+	tests := []TestCase{
+		{"[core]", "core", ""},
+		{"[ Core ]", "core", ""},
+		{`[remote "Origin"]`, "remote.Origin", ""},
+		{`[remote "a\"b"]`, `remote.a"b`, ""},
+		{`[remote "a]b"]`, "remote.a]b", ""},
+		{"[remote.origin]", "remote.origin", ""},
+		{"[core] excludesFile = x]", "core", " excludesFile = x]"},
+	}
+
+	for _, test := range tests {
+		section, rest := parseConfigSection(test.input)
+		assert.Equal(t, test.expected, section, test.input)
+		assert.Equal(t, test.rest, rest, test.input)
+	}
+}
+
+func TestConfigValueContinues(t *testing.T) {
+	assert.True(t, configValueContinues(` a \`))
+	assert.True(t, configValueContinues(` "a # b \`))
+	assert.False(t, configValueContinues(` a \\`))
+	assert.False(t, configValueContinues(` a # see C:\`))
+	assert.False(t, configValueContinues(` a ; \`))
+}
+
+func TestReadGitConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	included := filepath.Join(dir, "included")
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+
+	assert.NoError(t, os.WriteFile(included, []byte("[user]\n\tname = included\n"), 0644))
+	assert.NoError(t, os.WriteFile(first, []byte(
+		"# comment\n"+
+			"[core]\n"+
+			"\texcludesFile = first\n"+
+			"\tignoreCase\n"+
+			"[include]\n"+
+			"\tpath = included\n",
+	), 0644))
+	assert.NoError(t, os.WriteFile(second, []byte(
+		"[CORE]\r\n"+
+			"\tExcludesFile = \"second \\\n"+
+			"continued\"\r\n",
+	), 0644))
+
+	config, err := readGitConfigFiles(first, filepath.Join(dir, "missing"), second)
+	assert.NoError(t, err)
+	assert.Equal(t, gitConfig{
+		"core.excludesfile": "second continued",
+		"core.ignorecase":   "true",
+		"include.path":      "included",
+		"user.name":         "included",
+	}, config)
+}
+
+func TestReadGitConfigFiles_Comments(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config")
+
+	// a backslash at the end of a comment doesn't continue it
+	assert.NoError(t, os.WriteFile(filename, []byte(
+		"[user]\n"+
+			"# see C:\\\n"+
+			"[core]\n"+
+			"\texcludesFile = /tmp/x # C:\\\n"+
+			"\tignoreCase\n"+
+			"[alias] ; C:\\\n"+
+			"\tst = status\n",
+	), 0644))
+
+	config, err := readGitConfigFiles(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, gitConfig{
+		"core.excludesfile": "/tmp/x",
+		"core.ignorecase":   "true",
+		"alias.st":          "status",
+	}, config)
+}
+
+func TestReadGitConfigFiles_KeyAfterHeader(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config")
+
+	assert.NoError(t, os.WriteFile(filename, []byte(
+		"[core] excludesFile = /tmp/x\n"+
+			"[user]name = a\n"+
+			"[alias \"x]\"]\tst\n",
+	), 0644))
+
+	config, err := readGitConfigFiles(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, gitConfig{
+		"core.excludesfile": "/tmp/x",
+		"user.name":         "a",
+		"alias.x].st":       "true",
+	}, config)
+}
+
+func TestFindGitDir(t *testing.T) {
+	root := createTree(t, map[string]string{
+		"repo/.git/":                       "",
+		"main/.git/worktrees/wt/commondir": "../..\n",
+		"wt/.git":                          "gitdir: ../main/.git/worktrees/wt\n",
+		"broken/.git":                      "nonsense\n",
+	})
+
+	gitDir, err := findGitDir(filepath.Join(root, "repo"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "repo", ".git"), gitDir)
+
+	gitDir, err = findGitDir(filepath.Join(root, "wt"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "main", ".git", "worktrees", "wt"), gitDir)
+	assert.Equal(t, filepath.Join(root, "main", ".git"), findCommonDir(gitDir))
+
+	_, err = findGitDir(filepath.Join(root, "broken"))
+	assert.Error(t, err)
+
+	_, err = findGitDir(filepath.Join(root, "missing"))
+	assert.Error(t, err)
+}
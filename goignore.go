@@ -1,17 +1,31 @@
+// Package goignore matches paths against .gitignore patterns, the same way git does
+//
+// GitIgnore, Stack and Repository are safe for concurrent use by multiple goroutines once they are created,
+// every method which matches paths can be called in parallel, including with the cache of WithDirCache()
+// Only Stack.Push must not be called while the Stack is in use
 package goignore
 
 import (
+	"bufio"
 	"errors"
+	"io"
+	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 // this is my own implementation of strings.Split()
 // for my use case, this is better than the stdlib one
 func mySplit(s string, sep byte) []string {
+	return mySplitBuf(s, sep, make([]string, 0, 32))
+}
+
+// Same as mySplit, but appends the parts to buf, it doesn't allocate if buf has enough capacity
+func mySplitBuf(s string, sep byte, buf []string) []string {
 	l := 0
-	buf := make([]string, 0, 32)
 	for {
 		pos := strings.IndexByte(s[l:], sep)
 
@@ -57,16 +71,35 @@ type ruleComponent struct {
 	Star         bool
 }
 
+// The common shapes of rules, which are matched without interpreting their components
+type ruleKind byte
+
+const (
+	generalRule      ruleKind = iota // anything else, matched by matchAllComponents
+	nameRule                         // an unanchored name without wildcards, like "node_modules"
+	extensionRule                    // an unanchored "*.ext", like "*.o" or "*.tar.gz"
+	anchoredNameRule                 // a name without wildcards at the top, like "/build"
+)
+
 // Represents a single rule in a .gitignore file
 // Components is a list of path components to match against
 // Negate is true if the rule negates the match (i.e. starts with '!')
 // OnlyDirectory is true if the rule matches only directories (i.e. ends with '/')
 // Relative is true if the rule is relative (i.e. starts with '/')
+// Pattern, Source and Line record where the rule came from
+// Literal is the name for name rules, and the suffix (starting with '.') for extension rules
+// TrimmedSpaces is true if unescaped trailing spaces were removed from the line, see Lint()
 type rule struct {
 	Components    []ruleComponent
 	Negate        bool
 	OnlyDirectory bool
 	Relative      bool
+	Pattern       string
+	Source        string
+	Line          int
+	Kind          ruleKind
+	Literal       string
+	TrimmedSpaces bool
 }
 
 func selectorMatch(c byte, selector string) bool {
@@ -100,7 +133,29 @@ func selectorMatch(c byte, selector string) bool {
 	}
 }
 
-func makeRuleComponent(component string) (ruleComponent, error) {
+// Returned by makeRuleComponent for malformed components
+// offset is the position of the problem in the component
+type componentError struct {
+	offset int
+	reason string
+}
+
+func (e *componentError) Error() string {
+	return e.reason
+}
+
+// Folds ASCII letters to lowercase, other bytes are left alone
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
+
+// Creates a rule component from a part of a pattern
+// If caseFold is true, the component has to be matched against lowercase paths, see foldPathComponents()
+// Like in git, uppercase letters which are escaped, or appear on their own in a character class, never match then
+func makeRuleComponent(component string, caseFold bool) (ruleComponent, error) {
 	instructions := make([]ruleInstruction, 0, 8)
 	r := 0
 
@@ -139,12 +194,23 @@ func makeRuleComponent(component string) (ruleComponent, error) {
 				Type: questionmark,
 			})
 			continue
+		case '\\':
+			if caseFold && r+1 < len(component) && component[r+1] != toLowerASCII(component[r+1]) {
+				r += 2
+				// an empty character class, it never matches
+				instructions = append(instructions, ruleInstruction{
+					Type:    charClass,
+					Pattern: string(make([]byte, 32)),
+				})
+				continue
+			}
 		case '[':
+			start := r
 			r++
 			var bitset [32]byte
 
 			if r >= len(component) {
-				return ruleComponent{}, errors.New("unclosed character class")
+				return ruleComponent{}, &componentError{offset: start, reason: "unclosed character class"}
 			}
 
 			negate := false
@@ -153,7 +219,7 @@ func makeRuleComponent(component string) (ruleComponent, error) {
 				negate = true
 				r++
 				if r >= len(component) {
-					return ruleComponent{}, errors.New("unclosed character class")
+					return ruleComponent{}, &componentError{offset: start, reason: "unclosed character class"}
 				}
 			}
 
@@ -164,8 +230,10 @@ func makeRuleComponent(component string) (ruleComponent, error) {
 			}
 
 			for r < len(component) && component[r] != ']' {
-				// handle escaping
+				// handle escaping, the escaped character is a member of the class
 				if component[r] == '\\' && r+1 < len(component) {
+					escaped := component[r+1]
+					bitset[escaped/8] |= (1 << (escaped % 8))
 					r += 2
 					continue
 				}
@@ -178,13 +246,14 @@ func makeRuleComponent(component string) (ruleComponent, error) {
 					}
 
 					if s >= len(component) || s < r+2 {
-						return ruleComponent{}, errors.New("unclosed character class")
+						return ruleComponent{}, &componentError{offset: start, reason: "unclosed character class"}
 					}
 
 					selector := component[r : s-1]
 
 					for i := 0; i < 256; i++ {
-						if selectorMatch(byte(i), selector) {
+						// the path is folded to lowercase, so lowercase letters have to match [:upper:]
+						if selectorMatch(byte(i), selector) || (caseFold && selector == "upper" && selectorMatch(byte(i), "lower")) {
 							bitset[i/8] |= (1 << (uint(i) % 8))
 						}
 					}
@@ -202,6 +271,14 @@ func makeRuleComponent(component string) (ruleComponent, error) {
 						}
 						bitset[b/8] |= (1 << (uint(b) % 8))
 					}
+					if caseFold {
+						// the path is folded to lowercase, so lowercase letters have to match uppercase ranges
+						for i := byte('a'); i <= 'z'; i++ {
+							if upper := i - ('a' - 'A'); a <= upper && upper <= b {
+								bitset[i/8] |= (1 << (i % 8))
+							}
+						}
+					}
 					r += 3
 					continue
 				}
@@ -211,7 +288,7 @@ func makeRuleComponent(component string) (ruleComponent, error) {
 			}
 
 			if r >= len(component) || component[r] != ']' {
-				return ruleComponent{}, errors.New("unclosed character class")
+				return ruleComponent{}, &componentError{offset: start, reason: "unclosed character class"}
 			}
 
 			r++ // skip closing ']'
@@ -233,11 +310,18 @@ func makeRuleComponent(component string) (ruleComponent, error) {
 
 		for r < len(component) && component[r] != '*' && component[r] != '?' && component[r] != '[' {
 			if component[r] == '\\' && r+1 < len(component) {
+				if caseFold && component[r+1] != toLowerASCII(component[r+1]) {
+					break // handled by the outer loop
+				}
 				patternBuilder.WriteByte(component[r+1])
 				r += 2
 				continue
 			}
-			patternBuilder.WriteByte(component[r])
+			if caseFold {
+				patternBuilder.WriteByte(toLowerASCII(component[r]))
+			} else {
+				patternBuilder.WriteByte(component[r])
+			}
 			r++
 		}
 
@@ -352,6 +436,26 @@ func matchAllComponents(path []string, components []ruleComponent) (matches bool
 // Tries to match the path against the rule
 // the function expects a buffer of sufficient size to get passed to it, this avoids excessive memory allocation
 func (r *rule) matchesPath(isDirectory bool, pathComponents []string) bool {
+	switch r.Kind {
+	case nameRule:
+		for j := 0; j < len(pathComponents); j++ {
+			if pathComponents[j] == r.Literal {
+				return j < len(pathComponents)-1 || !r.OnlyDirectory || isDirectory
+			}
+		}
+		return false
+	case extensionRule:
+		for j := 0; j < len(pathComponents); j++ {
+			if strings.HasSuffix(pathComponents[j], r.Literal) {
+				return j < len(pathComponents)-1 || !r.OnlyDirectory || isDirectory
+			}
+		}
+		return false
+	case anchoredNameRule:
+		return len(pathComponents) > 0 && pathComponents[0] == r.Literal &&
+			(len(pathComponents) > 1 || !r.OnlyDirectory || isDirectory)
+	}
+
 	if !r.Relative {
 		// stinky recursive step
 		for j := 0; j < len(pathComponents); j++ {
@@ -370,8 +474,117 @@ func (r *rule) matchesPath(isDirectory bool, pathComponents []string) bool {
 }
 
 // Stores a list of rules for matching paths against .gitignore patterns
+// It is never modified after compiling, so it is safe for concurrent use
 type GitIgnore struct {
-	rules []rule
+	rules    []rule
+	warnings []*PatternError
+	caseFold bool
+	// the rules only apply below this directory, it is folded to lowercase if caseFold is set
+	base []string
+	// set if the base directory is not a valid relative path, the rules apply to nothing then
+	invalidBase bool
+	// the verdicts of parent directories, nil unless WithDirCache() is given
+	dirCache *dirCache
+	// nil unless WithIndex() is given
+	index *ruleIndex
+}
+
+// Describes a malformed pattern
+// Line and Column are 1-based, Column is the byte offset of the problem in the line
+type PatternError struct {
+	Source  string
+	Line    int
+	Column  int
+	Pattern string
+	Reason  string
+}
+
+// Formats the error like compilers do, e.g. ".gitignore:3:5: unclosed character class: foo[a-z"
+func (e *PatternError) Error() string {
+	return e.Source + ":" + strconv.Itoa(e.Line) + ":" + strconv.Itoa(e.Column) + ": " + e.Reason + ": " + e.Pattern
+}
+
+// Returns the problems found in malformed patterns while compiling
+func (g *GitIgnore) Warnings() []*PatternError {
+	return g.warnings
+}
+
+func (g *GitIgnore) warningsError() error {
+	if len(g.warnings) == 0 {
+		return nil
+	}
+	errs := make([]error, len(g.warnings))
+	for i, warning := range g.warnings {
+		errs[i] = warning
+	}
+	return errors.Join(errs...)
+}
+
+// Describes the rule which decided whether a path is ignored
+// Pattern is the line as it was written (including a leading '!'), without trailing spaces
+// Source is the name of the file the rule was read from, it is empty for CompileIgnoreLines
+// Line is the 1-based line number of the rule in its source
+type Match struct {
+	Pattern string
+	Negate  bool
+	Source  string
+	Line    int
+}
+
+// Formats the match like `git check-ignore -v` does, e.g. ".gitignore:12:build/"
+func (m *Match) String() string {
+	return m.Source + ":" + strconv.Itoa(m.Line) + ":" + m.Pattern
+}
+
+func (r *rule) toMatch() *Match {
+	if r == nil {
+		return nil
+	}
+	return &Match{
+		Pattern: r.Pattern,
+		Negate:  r.Negate,
+		Source:  r.Source,
+		Line:    r.Line,
+	}
+}
+
+// The outcome of matching a path, see Check()
+type Result byte
+
+const (
+	NoMatch  Result = iota // no rule matched the path, or it is outside of the base directory
+	Ignored                // the deciding rule is not negated
+	Included               // the deciding rule is negated, the path was explicitly re-included
+)
+
+func (res Result) String() string {
+	switch res {
+	case NoMatch:
+		return "NoMatch"
+	case Ignored:
+		return "Ignored"
+	case Included:
+		return "Included"
+	default:
+		return "Result(" + strconv.Itoa(int(res)) + ")"
+	}
+}
+
+func (r *rule) toResult() Result {
+	switch {
+	case r == nil:
+		return NoMatch
+	case r.Negate:
+		return Included
+	default:
+		return Ignored
+	}
+}
+
+// Reports whether the line is only an optional '!' and slashes, like "//" or "!/"
+// git skips these lines, the rule would have no components and match everything otherwise
+func onlyNegationAndSlashes(pattern string) bool {
+	return strings.Trim(strings.TrimPrefix(pattern, "!"), "/") == ""
 }
 
 func trimUnescapedTrailingSpaces(s string) string {
@@ -397,51 +610,197 @@ func trimUnescapedTrailingSpaces(s string) string {
 }
 
 // Creates a Gitignore from a list of patterns (lines in a .gitignore file)
+// Malformed patterns never match anything, they can be listed with Warnings()
+// Use Compile() for more options
 func CompileIgnoreLines(patterns ...string) *GitIgnore {
+	return compileIgnoreLines(patterns, compileOptions{})
+}
+
+// Same as CompileIgnoreLines, but fails if any of the patterns are malformed
+// The returned error joins a *PatternError for every problem found
+func CompileIgnoreLinesStrict(patterns ...string) (*GitIgnore, error) {
+	return Compile(patterns, WithStrict())
+}
+
+func compileIgnoreLines(patterns []string, options compileOptions) *GitIgnore {
+	gitignore := newGitIgnore(len(patterns), options)
+
+	for i, pattern := range patterns {
+		gitignore.addLine(pattern, i+1, options)
+	}
+
+	return gitignore
+}
+
+func newGitIgnore(capacity int, options compileOptions) *GitIgnore {
 	gitignore := &GitIgnore{
-		rules: make([]rule, 0, len(patterns)),
+		rules:    make([]rule, 0, capacity),
+		caseFold: options.caseFold,
 	}
 
-	for _, pattern := range patterns {
-		// skip empty lines, comments, '!', '/', and trailing spaces which aren't escaped with a backslash like "\ ".
-		pattern = beforeFirstNullByte(pattern) // Remove anything after and including the first null-byte
-		pattern = strings.TrimRight(pattern, "\r\n")
-		pattern = trimUnescapedTrailingSpaces(pattern)
-		if pattern == "" || pattern == "!" || pattern == "/" || pattern[0] == '#' {
-			continue
+	if options.baseDir != "" {
+		base, _, ok := splitPath(options.baseDir)
+		if options.caseFold {
+			foldPathComponents(base)
 		}
+		gitignore.base = base
+		gitignore.invalidBase = !ok
+	}
+	if options.dirCacheSize > 0 {
+		gitignore.dirCache = newDirCache(options.dirCacheSize)
+	}
+	if options.index {
+		gitignore.index = newRuleIndex()
+	}
+	return gitignore
+}
 
-		rule := createRule(pattern)
+// Strips the base directory from the path, ok is false if the path is not below it
+// The path must already be folded if caseFold is set
+func (g *GitIgnore) relativeToBase(pathComponents []string) (relativeComponents []string, ok bool) {
+	if g.invalidBase {
+		return nil, false
+	}
+	if len(g.base) == 0 {
+		return pathComponents, true
+	}
+	if len(pathComponents) <= len(g.base) {
+		return nil, false
+	}
+	for i := range g.base {
+		if pathComponents[i] != g.base[i] {
+			return nil, false
+		}
+	}
+	return pathComponents[len(g.base):], true
+}
 
-		gitignore.rules = append(gitignore.rules, rule)
+// Reports whether the path is below the base directory set by WithBaseDir()
+// Paths that are not are never matched, it is always true without a base directory
+func (g *GitIgnore) AppliesTo(path string) bool {
+	pathComponents, _, ok := splitPath(path)
+	if !ok {
+		return false
 	}
+	if g.caseFold {
+		foldPathComponents(pathComponents)
+	}
+	_, ok = g.relativeToBase(pathComponents)
+	return ok
+}
 
-	return gitignore
+// Compiles a line of a .gitignore file and adds it to the rules, lineNumber is 1-based
+func (g *GitIgnore) addLine(pattern string, lineNumber int, options compileOptions) {
+	// skip empty lines, comments, the ones with only '!' and slashes, and trailing spaces which aren't escaped with a backslash like "\ ".
+	pattern = beforeFirstNullByte(pattern) // Remove anything after and including the first null-byte
+	pattern = strings.TrimRight(pattern, "\r\n")
+	untrimmedLength := len(pattern)
+	pattern = trimUnescapedTrailingSpaces(pattern)
+	if pattern == "" || pattern[0] == '#' {
+		return
+	}
+	if onlyNegationAndSlashes(pattern) {
+		// nothing to compile, but it may have been meant to match something
+		g.warnings = append(g.warnings, &PatternError{
+			Source:  options.source,
+			Line:    lineNumber,
+			Column:  1,
+			Pattern: pattern,
+			Reason:  "only '!' and slashes",
+		})
+		return
+	}
+
+	rule, errs := createRule(pattern, options.caseFold)
+	rule.Pattern = pattern
+	rule.Source = options.source
+	rule.Line = lineNumber
+	rule.TrimmedSpaces = len(pattern) < untrimmedLength
+
+	for _, err := range errs {
+		err.Source = options.source
+		err.Line = lineNumber
+		err.Pattern = pattern
+		g.warnings = append(g.warnings, err)
+	}
+
+	g.rules = append(g.rules, rule)
+	if g.index != nil {
+		g.index.add(&g.rules[len(g.rules)-1], len(g.rules)-1)
+	}
+	// the cached verdicts may have changed
+	g.ClearCache()
+}
+
+// Same as Compile, but reads the lines from r
+// A UTF-8 byte order mark at the start is skipped like git does, and lines may be of any length
+func CompileIgnoreReader(r io.Reader, opts ...Option) (*GitIgnore, error) {
+	return compileReader(r, newCompileOptions(opts))
+}
+
+func compileReader(r io.Reader, options compileOptions) (*GitIgnore, error) {
+	gitignore := newGitIgnore(0, options)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), math.MaxInt) // don't limit the length of lines
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\xef\xbb\xbf")
+		}
+		gitignore.addLine(line, lineNumber, options)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return checkStrict(gitignore, options)
+}
+
+// Same as Compile, but reads from a file
+// The source of the rules is the filename, unless WithSource() is given
+func CompileIgnoreFile(filename string, opts ...Option) (*GitIgnore, error) {
+	file, err := os.Open(filename)
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return CompileIgnoreReader(file, append([]Option{WithSource(filename)}, opts...)...)
 }
 
-// Same as CompileIgnoreLines, but reads from a file
-func CompileIgnoreFile(filename string) (*GitIgnore, error) {
-	lines, err := os.ReadFile(filename)
+// Same as CompileIgnoreFile, but reads the file from fsys, e.g. an embed.FS
+func CompileIgnoreFS(fsys fs.FS, name string, opts ...Option) (*GitIgnore, error) {
+	file, err := fsys.Open(name)
 
 	if err != nil {
 		return nil, err
 	}
-	return CompileIgnoreLines(strings.Split(string(lines), "\n")...), nil
+	defer file.Close()
+	return CompileIgnoreReader(file, append([]Option{WithSource(name)}, opts...)...)
 }
 
 // create a rule from a pattern
-func createRule(pattern string) rule {
+// errs has an entry for each malformed part of the pattern, only Column and Reason are filled in
+// the malformed components are left empty, so they never match anything
+func createRule(pattern string, caseFold bool) (r rule, errs []*PatternError) {
 	negate := false
 	onlyDirectory := false
 	relative := false
+	offset := 0 // the position of pattern in the original line
 	if pattern[0] == '!' {
 		negate = true
 		pattern = pattern[1:] // skip the '!'
+		offset++
 	}
 
 	if pattern[0] == '/' {
 		relative = true
 		pattern = pattern[1:] // skip the '/'
+		offset++
 	}
 
 	// check if the pattern ends with a '/', which means it only matches directories
@@ -454,19 +813,89 @@ func createRule(pattern string) rule {
 
 	ruleComponents := make([]ruleComponent, len(components))
 
+	pos := 0 // the position of the current component in pattern
 	for i := 0; i < len(components); i++ {
-		comp, err := makeRuleComponent(components[i])
+		for pattern[pos] == '/' {
+			pos++
+		}
+
+		comp, err := makeRuleComponent(components[i], caseFold)
 		if err == nil {
 			ruleComponents[i] = comp
+		} else if compErr, ok := err.(*componentError); ok {
+			errs = append(errs, &PatternError{
+				Column: offset + pos + compErr.offset + 1,
+				Reason: compErr.reason,
+			})
 		}
+
+		pos += len(components[i])
 	}
 
-	return rule{
+	// git never matches a pattern ending in an unescaped backslash
+	if n := len(components); n > 0 && len(errs) == 0 && endsWithUnescapedBackslash(pattern) {
+		ruleComponents[n-1] = ruleComponent{}
+		errs = append(errs, &PatternError{
+			Column: offset + len(pattern),
+			Reason: "trailing backslash",
+		})
+	}
+
+	r = rule{
 		Components:    ruleComponents,
 		Negate:        negate,
 		OnlyDirectory: onlyDirectory,
 		Relative:      relative || len(components) > 1,
 	}
+	r.Kind, r.Literal = classifyRule(&r)
+	return r, errs
+}
+
+// Finds out if the rule has one of the common shapes which can be matched without interpreting it
+func classifyRule(r *rule) (kind ruleKind, literal string) {
+	if len(r.Components) != 1 {
+		return generalRule, ""
+	}
+	component := &r.Components[0]
+
+	if name, ok := literalComponent(component); ok {
+		if r.Relative {
+			return anchoredNameRule, name
+		}
+		return nameRule, name
+	}
+	if suffix, ok := suffixComponent(component); ok && !r.Relative {
+		return extensionRule, suffix
+	}
+	return generalRule, ""
+}
+
+// Returns the string a component matches if it has no wildcards
+func literalComponent(component *ruleComponent) (string, bool) {
+	if component.Star || component.Starstar || len(component.Instructions) != 1 || component.Instructions[0].Type != raw {
+		return "", false
+	}
+	return component.Instructions[0].Pattern, true
+}
+
+// Returns the suffix of a component like "*.o", which matches every name ending in ".o"
+func suffixComponent(component *ruleComponent) (string, bool) {
+	if component.Star || component.Starstar || len(component.Instructions) != 2 {
+		return "", false
+	}
+	first, second := component.Instructions[0], component.Instructions[1]
+	if first.Type != star || second.Type != raw || second.Pattern == "" || second.Pattern[0] != '.' {
+		return "", false
+	}
+	return second.Pattern, true
+}
+
+func endsWithUnescapedBackslash(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
 }
 
 // Copied from: https://cs.opensource.google/go/go/+/refs/tags/go1.26.0:src/io/fs/fs.go;l=64
@@ -507,51 +936,230 @@ func beforeFirstNullByte(s string) string {
 	return s[:firstNullByte]
 }
 
-// Tries to match the path to all the rules in the gitignore
-func (g *GitIgnore) MatchesPath(path string) bool {
+// Cleans the path and converts it to use forward slashes, "." is returned for the root
+// isDir is true if the path has a trailing slash, ok is false if the path can never match any rule
+func cleanPath(path string) (cleaned string, isDir bool, ok bool) {
 	if strings.IndexByte(path, '\x00') != -1 {
-		return false
+		return "", false, false
 	}
 
-	// TODO: check if path actually points to a directory on the filesystem
-	isDir := strings.HasSuffix(path, "/")
+	isDir = strings.HasSuffix(path, "/")
 	path = filepath.Clean(path) // Removes trailing slashes, except for roots like "/", "C:\"
 	path = filepath.ToSlash(path)
 	if path == "." {
-		path = "/"
 		isDir = true
 	}
 	if !validPathBadUtf8Allowed(path) {
-		return false
+		return "", false, false
 	}
-	pathComponents := mySplit(path, '/')
+	return path, isDir, true
+}
 
-	// First, if there are any parent directories (more than 1 path component), check if they match.
-	for j := 0; j < len(pathComponents)-1; j++ {
-		for i := len(g.rules) - 1; i >= 0; i-- {
-			rule := g.rules[i]
-			if rule.matchesPath(true /* Makes no difference? */, pathComponents[:j+1]) {
-				if rule.Negate {
-					break // Undecided.
-				} else {
-					return true
+// Splits a path returned by cleanPath into its components
+func splitCleanPath(path string) []string {
+	if path == "." {
+		return []string{}
+	}
+	return mySplit(path, '/')
+}
+
+// Splits a path into its components, isDir is true if the path has a trailing slash
+// ok is false if the path can never match any rule
+func splitPath(path string) (pathComponents []string, isDir bool, ok bool) {
+	path, isDir, ok = cleanPath(path)
+	if !ok {
+		return nil, false, false
+	}
+	return splitCleanPath(path), isDir, true
+}
+
+// Reports whether foldPathComponents would leave the path components unchanged
+func isFolded(pathComponents []string) bool {
+	for _, component := range pathComponents {
+		for j := 0; j < len(component); j++ {
+			if component[j] != toLowerASCII(component[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Folds the path components to lowercase in place, for matching rules compiled with caseFold
+func foldPathComponents(pathComponents []string) {
+	for i, component := range pathComponents {
+		for j := 0; j < len(component); j++ {
+			if component[j] != toLowerASCII(component[j]) {
+				folded := []byte(component)
+				for k := j; k < len(folded); k++ {
+					folded[k] = toLowerASCII(folded[k])
 				}
+				pathComponents[i] = string(folded)
+				break
 			}
 		}
 	}
+}
 
-	// If no parent directories match, we must check if the whole path matches.
+// Implemented by filesystems which can stat a file without following symbolic links, like os.DirFS since Go 1.25
+type lstatFS interface {
+	fs.FS
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// Finds out whether the path returned by cleanPath is a directory in fsys
+// Symbolic links are not followed if fsys supports it, git never treats them as directories
+// ok is false if the path could not be found
+func isDirFS(fsys fs.FS, path string) (isDir bool, ok bool) {
+	var info fs.FileInfo
+	var err error
+	if lfsys, isLstatFS := fsys.(lstatFS); isLstatFS {
+		info, err = lfsys.Lstat(path)
+	} else {
+		info, err = fs.Stat(fsys, path)
+	}
+	if err != nil {
+		return false, false
+	}
+	return info.IsDir(), true
+}
+
+// Returns the last rule that matches the path, or nil if none of them do
+// Parent directories of the path are not checked
+func (g *GitIgnore) lastMatchingRule(isDir bool, pathComponents []string) *rule {
+	if g.index != nil {
+		return g.index.lastMatchingRule(g.rules, isDir, pathComponents)
+	}
 	for i := len(g.rules) - 1; i >= 0; i-- {
-		rule := g.rules[i]
+		if g.rules[i].matchesPath(isDir, pathComponents) {
+			return &g.rules[i]
+		}
+	}
+	return nil
+}
 
-		if rule.matchesPath(isDir, pathComponents) {
-			if rule.Negate {
-				return false
-			} else {
-				return true
+// Returns the rule which decides whether the path is ignored, or nil if no rule matches it
+// pathComponents may be modified
+func (g *GitIgnore) decidingRule(pathComponents []string, isDir bool) *rule {
+	if g.caseFold {
+		foldPathComponents(pathComponents)
+	}
+	pathComponents, ok := g.relativeToBase(pathComponents)
+	if !ok {
+		return nil
+	}
+
+	if g.dirCache != nil {
+		if len(pathComponents) > 1 {
+			var keyBuffer [256]byte
+			parent := pathComponents[:len(pathComponents)-1]
+			if rule := g.cachedDirVerdict(parent, appendJoinedPath(keyBuffer[:0], parent)); rule != nil {
+				return rule
 			}
 		}
+		return g.lastMatchingRule(isDir, pathComponents)
 	}
 
-	return false
+	// First, if there are any parent directories (more than 1 path component), check if they match.
+	for j := 0; j < len(pathComponents)-1; j++ {
+		rule := g.lastMatchingRule(true /* Makes no difference? */, pathComponents[:j+1])
+		if rule != nil && !rule.Negate {
+			return rule
+		}
+		// A negated rule leaves the parent undecided.
+	}
+
+	// If no parent directories match, we must check if the whole path matches.
+	return g.lastMatchingRule(isDir, pathComponents)
+}
+
+// Tries to match the path to all the rules in the gitignore
+// The path is treated as a directory if it has a trailing slash
+func (g *GitIgnore) MatchesPath(path string) bool {
+	pathComponents, isDir, ok := splitPath(path)
+	if !ok {
+		return false
+	}
+	rule := g.decidingRule(pathComponents, isDir)
+	return rule != nil && !rule.Negate
+}
+
+// Same as MatchesPath, but isDir tells whether the path is a directory, a trailing slash makes no difference
+// Useful when the caller already knows it, e.g. from fs.DirEntry.IsDir()
+func (g *GitIgnore) MatchesPathIsDir(path string, isDir bool) bool {
+	pathComponents, _, ok := splitPath(path)
+	if !ok {
+		return false
+	}
+	rule := g.decidingRule(pathComponents, isDir)
+	return rule != nil && !rule.Negate
+}
+
+// Same as MatchesPath, but looks up the path in fsys to find out whether it is a directory
+// If the path does not exist in fsys, it is treated as a directory only if it has a trailing slash
+func (g *GitIgnore) MatchesPathFS(fsys fs.FS, path string) bool {
+	path, isDir, ok := cleanPath(path)
+	if !ok {
+		return false
+	}
+	if isDirOnFS, found := isDirFS(fsys, path); found {
+		isDir = isDirOnFS
+	}
+	rule := g.decidingRule(splitCleanPath(path), isDir)
+	return rule != nil && !rule.Negate
+}
+
+// Returns the rule which decides whether the path is ignored, or nil if no rule matches it
+// The path is ignored if the returned rule is not negated
+func (g *GitIgnore) Explain(path string) *Match {
+	pathComponents, isDir, ok := splitPath(path)
+	if !ok {
+		return nil
+	}
+	return g.decidingRule(pathComponents, isDir).toMatch()
+}
+
+// Same as MatchesPath, but tells apart paths re-included by a negated rule from paths no rule matched
+// Useful for layering matchers, where only NoMatch should fall through to the next one
+func (g *GitIgnore) Check(path string) Result {
+	pathComponents, isDir, ok := splitPath(path)
+	if !ok {
+		return NoMatch
+	}
+	return g.decidingRule(pathComponents, isDir).toResult()
+}
+
+// Same as Check, but isDir tells whether the path is a directory, a trailing slash makes no difference
+func (g *GitIgnore) CheckIsDir(path string, isDir bool) Result {
+	pathComponents, _, ok := splitPath(path)
+	if !ok {
+		return NoMatch
+	}
+	return g.decidingRule(pathComponents, isDir).toResult()
+}
+
+// Same as MatchesPathIsDir, but the path must already be clean: relative, separated by '/',
+// without empty, "." or ".." components and without a trailing slash, like the paths fs.WalkDir passes on
+// "." is the root, and the path is not checked, so other paths give undefined results
+// It doesn't allocate memory, unless the path has more than 32 components, case folding changes it,
+// or the directory cache of WithDirCache() has to remember a new directory
+func (g *GitIgnore) MatchesCleanPath(path string, isDir bool) bool {
+	var buffer [32]string
+	pathComponents := buffer[:0]
+	if path != "." {
+		pathComponents = mySplitBuf(path, '/', pathComponents)
+	}
+	rule := g.decidingRule(pathComponents, isDir)
+	return rule != nil && !rule.Negate
+}
+
+// Same as MatchesPathIsDir, but for a path which is already split into its components, like []string{"src", "main.go"}
+// The components must not be empty, "." or "..", or contain '/', they are not checked or modified
+// It doesn't allocate memory, unless case folding changes the path or the directory cache has to remember a new directory
+func (g *GitIgnore) MatchesComponents(pathComponents []string, isDir bool) bool {
+	if g.caseFold && !isFolded(pathComponents) {
+		pathComponents = append([]string(nil), pathComponents...)
+	}
+	rule := g.decidingRule(pathComponents, isDir)
+	return rule != nil && !rule.Negate
 }
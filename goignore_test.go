@@ -1,13 +1,17 @@
 package goignore
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -59,7 +63,7 @@ func ExampleCompileIgnoreLines() {
 func ExampleCompileIgnoreFile() {
 	ignoreObject, err := CompileIgnoreFile(".gitignore")
 
-	// err returns an error from os.ReadFile()
+	// err returns an error from opening or reading the file
 	if err != nil {
 		fmt.Println("Error reading .gitignore file:", err)
 		return
@@ -324,6 +328,18 @@ func TestSingleSlashRule(t *testing.T) {
 	assert.Equal(t, false, ignoreObject.MatchesPath("/"), "/ should not match")
 }
 
+func TestOnlySlashesRule(t *testing.T) {
+	// git skips the lines with only '!' and slashes
+	ignoreObject := CompileIgnoreLines("*", "!/", "!//")
+	assert.Equal(t, true, ignoreObject.MatchesPath("file.txt"), "file.txt should match")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b"), "a/b should match")
+
+	ignoreObject = CompileIgnoreLines("//", "///")
+	assert.Equal(t, false, ignoreObject.MatchesPath("file.txt"), "file.txt should not match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/b/"), "a/b/ should not match")
+	assert.Nil(t, ignoreObject.Explain("a"))
+}
+
 func TestValidReinclude(t *testing.T) {
 	ignoreObject := CompileIgnoreLines(
 		"folder",
@@ -348,6 +364,41 @@ func TestInvalidReinclude(t *testing.T) {
 	assert.Equal(t, true, ignoreObject.MatchesPath("folder/subfolder/file.txt"), "folder/subfolder/file.txt should match")
 }
 
+func TestExplain(t *testing.T) {
+	ignoreObject := CompileIgnoreLines(
+		"# build output",
+		"build/",
+		"*.o  ",
+		"!keep.o",
+	)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	match := ignoreObject.Explain("build/x/y")
+	assert.Equal(t, &Match{Pattern: "build/", Negate: false, Line: 2}, match)
+	assert.Equal(t, ":2:build/", match.String())
+
+	match = ignoreObject.Explain("a.o")
+	assert.Equal(t, &Match{Pattern: "*.o", Negate: false, Line: 3}, match)
+
+	match = ignoreObject.Explain("keep.o")
+	assert.Equal(t, &Match{Pattern: "!keep.o", Negate: true, Line: 4}, match)
+
+	assert.Nil(t, ignoreObject.Explain("nothing"), "nothing should not match any rule")
+}
+
+func TestExplain_File(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".gitignore")
+	assert.NoError(t, os.WriteFile(filename, []byte("a\nb\n"), 0644))
+
+	ignoreObject, err := CompileIgnoreFile(filename)
+	assert.NoError(t, err)
+
+	match := ignoreObject.Explain("b")
+	assert.Equal(t, &Match{Pattern: "b", Negate: false, Source: filename, Line: 2}, match)
+	assert.Equal(t, filename+":2:b", match.String())
+}
+
 func TestWildCardFiles(t *testing.T) {
 	gitIgnore := []string{"*.swp", "/foo/*.wat", "bar/*.txt"}
 	ignoreObject := CompileIgnoreLines(gitIgnore...)
@@ -399,6 +450,81 @@ func TestDirOnlyMatching(t *testing.T) {
 	assert.Equal(t, true, ignoreObject.MatchesPath("bar/foo"), "should match nested files in bar")
 }
 
+func TestMatchesPathIsDir(t *testing.T) {
+	ignoreObject := CompileIgnoreLines("build/", "!keep/")
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	assert.Equal(t, true, ignoreObject.MatchesPathIsDir("build", true), "build should match as a directory")
+	assert.Equal(t, false, ignoreObject.MatchesPathIsDir("build", false), "build should not match as a file")
+	assert.Equal(t, false, ignoreObject.MatchesPathIsDir("build/", false), "build/ should not match as a file")
+	assert.Equal(t, true, ignoreObject.MatchesPathIsDir("a/build", true), "a/build should match as a directory")
+	assert.Equal(t, true, ignoreObject.MatchesPathIsDir("build/file", false), "build/file should match")
+	assert.Equal(t, false, ignoreObject.MatchesPathIsDir("a/\x00", true), "paths with null bytes should not match")
+}
+
+func TestCheck(t *testing.T) {
+	ignoreObject := CompileIgnoreLines("*.o", "!keep.o", "build/", "!build/x")
+
+	assert.Equal(t, Ignored, ignoreObject.Check("a.o"))
+	assert.Equal(t, Included, ignoreObject.Check("keep.o"))
+	assert.Equal(t, NoMatch, ignoreObject.Check("a.c"))
+	assert.Equal(t, Ignored, ignoreObject.Check("build/x"), "a file in an ignored directory can't be re-included")
+	assert.Equal(t, NoMatch, ignoreObject.Check("a/\x00"))
+	assert.Equal(t, Ignored, ignoreObject.CheckIsDir("build", true))
+	assert.Equal(t, NoMatch, ignoreObject.CheckIsDir("build", false))
+
+	ignoreObject, err := Compile([]string{"*.o"}, WithBaseDir("sub"))
+	assert.NoError(t, err)
+	assert.Equal(t, Ignored, ignoreObject.Check("sub/a.o"))
+	assert.Equal(t, NoMatch, ignoreObject.Check("a.o"))
+
+	assert.Equal(t, "Included", Included.String())
+	assert.Equal(t, "Result(7)", Result(7).String())
+}
+
+// Layers a more specific matcher over a general one, only falling through when the specific one has no opinion
+func TestCheck_Layering(t *testing.T) {
+	global := CompileIgnoreLines("*.log", "*.tmp")
+	local := CompileIgnoreLines("!important.log", "*.out")
+
+	check := func(path string) bool {
+		if result := local.Check(path); result != NoMatch {
+			return result == Ignored
+		}
+		return global.Check(path) == Ignored
+	}
+
+	assert.Equal(t, true, check("debug.log"), "debug.log should be ignored")
+	assert.Equal(t, false, check("important.log"), "important.log should not be ignored")
+	assert.Equal(t, true, check("a.out"), "a.out should be ignored")
+	assert.Equal(t, true, check("a.tmp"), "a.tmp should be ignored")
+	assert.Equal(t, false, check("a.c"), "a.c should not be ignored")
+}
+
+func TestMatchesPathFS(t *testing.T) {
+	ignoreObject := CompileIgnoreLines("build/")
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	fsys := fstest.MapFS{
+		"build/out.o": &fstest.MapFile{},
+		"src/build":   &fstest.MapFile{},
+		"src/a/build": &fstest.MapFile{Mode: fs.ModeDir},
+	}
+
+	assert.Equal(t, true, ignoreObject.MatchesPathFS(fsys, "build"), "build should match")
+	assert.Equal(t, true, ignoreObject.MatchesPathFS(fsys, "build/"), "build/ should match")
+	assert.Equal(t, true, ignoreObject.MatchesPathFS(fsys, "build/out.o"), "build/out.o should match")
+	assert.Equal(t, false, ignoreObject.MatchesPathFS(fsys, "src/build"), "src/build should not match, it is a file")
+	assert.Equal(t, false, ignoreObject.MatchesPathFS(fsys, "src/build/"), "src/build/ should not match, it is a file")
+	assert.Equal(t, true, ignoreObject.MatchesPathFS(fsys, "src/a/build"), "src/a/build should match")
+
+	// Paths missing from the filesystem fall back to checking for a trailing slash
+	assert.Equal(t, false, ignoreObject.MatchesPathFS(fsys, "missing/build"), "missing/build should not match")
+	assert.Equal(t, true, ignoreObject.MatchesPathFS(fsys, "missing/build/"), "missing/build/ should match")
+}
+
 func TestCharacterClasses(t *testing.T) {
 	gitIgnore := []string{"[a-zA-Z*!]-files"}
 	ignoreObject := CompileIgnoreLines(gitIgnore...)
@@ -442,6 +568,191 @@ func TestCharacterClasses(t *testing.T) {
 	assert.Equal(t, true, ignoreObject.MatchesPath("6.txt"), "should match 6.txt")
 	assert.Equal(t, false, ignoreObject.MatchesPath("z.txt"), "should not match z.txt")
 	assert.Equal(t, true, ignoreObject.MatchesPath("a.txt"), "should match a.txt")
+
+	// escaped characters are members of the class, like in git
+	gitIgnore = []string{"x[\\a]", "y[\\]]", "z[a\\-c]"}
+	ignoreObject = CompileIgnoreLines(gitIgnore...)
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("xa"), "should match xa")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x\\"), "should not match x\\")
+	assert.Equal(t, true, ignoreObject.MatchesPath("y]"), "should match y]")
+	assert.Equal(t, true, ignoreObject.MatchesPath("z-"), "should match z-")
+	assert.Equal(t, false, ignoreObject.MatchesPath("zb"), "should not match zb")
+}
+
+func TestCompile_Source(t *testing.T) {
+	ignoreObject, err := Compile([]string{"a", "[b"}, WithSource("sub/.gitignore"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "sub/.gitignore:1:a", ignoreObject.Explain("a").String())
+	assert.Equal(t, "sub/.gitignore:2:1: unclosed character class: [b", ignoreObject.Warnings()[0].Error())
+}
+
+func TestCompileIgnoreFile_Options(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".gitignore")
+	assert.NoError(t, os.WriteFile(filename, []byte("Foo\n[b\n"), 0644))
+
+	ignoreObject, err := CompileIgnoreFile(filename, WithCaseFold(), WithSource(".gitignore"))
+	assert.NoError(t, err)
+	assert.Equal(t, ".gitignore:1:Foo", ignoreObject.Explain("foo").String())
+
+	ignoreObject, err = CompileIgnoreFile(filename, WithStrict())
+	assert.Nil(t, ignoreObject, "Returned object should be nil")
+	assert.EqualError(t, err, filename+":2:1: unclosed character class: [b")
+}
+
+func TestCompileIgnoreReader(t *testing.T) {
+	longName := strings.Repeat("a", 1<<20)
+	content := "\xef\xbb\xbfbom\r\n" +
+		"# comment\r\n" +
+		"crlf\r\n" +
+		longName + "\n" +
+		"\xef\xbb\xbfnot-bom\n" +
+		"last"
+
+	ignoreObject, err := CompileIgnoreReader(strings.NewReader(content), WithSource("reader"))
+	assert.NoError(t, err)
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	assert.Equal(t, "reader:1:bom", ignoreObject.Explain("bom").String())
+	assert.Equal(t, "reader:3:crlf", ignoreObject.Explain("crlf").String())
+	assert.Equal(t, true, ignoreObject.MatchesPath(longName), "the long line should match")
+	assert.Equal(t, 4, ignoreObject.Explain(longName).Line)
+	assert.Equal(t, false, ignoreObject.MatchesPath("not-bom"), "the byte order mark is only skipped on the first line")
+	assert.Equal(t, true, ignoreObject.MatchesPath("\xef\xbb\xbfnot-bom"), "the byte order mark is only skipped on the first line")
+	assert.Equal(t, "reader:6:last", ignoreObject.Explain("last").String())
+}
+
+func TestCompileIgnoreReader_Errors(t *testing.T) {
+	readErr := errors.New("read error")
+	ignoreObject, err := CompileIgnoreReader(iotest.ErrReader(readErr))
+	assert.ErrorIs(t, err, readErr)
+	assert.Nil(t, ignoreObject, "Returned object should be nil")
+
+	ignoreObject, err = CompileIgnoreReader(strings.NewReader("ok\n[a-z\n"), WithStrict())
+	assert.EqualError(t, err, ":2:1: unclosed character class: [a-z")
+	assert.Nil(t, ignoreObject, "Returned object should be nil")
+}
+
+func TestCompileIgnoreFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"defaults/.gitignore": {Data: []byte("*.o\n")},
+	}
+
+	ignoreObject, err := CompileIgnoreFS(fsys, "defaults/.gitignore")
+	assert.NoError(t, err)
+	assert.Equal(t, "defaults/.gitignore:1:*.o", ignoreObject.Explain("a.o").String())
+
+	ignoreObject, err = CompileIgnoreFS(fsys, "missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Nil(t, ignoreObject, "Returned object should be nil")
+}
+
+// The expected results were taken from `git -c core.ignoreCase=true check-ignore`
+func TestCaseFold(t *testing.T) {
+	ignoreObject, err := Compile([]string{
+		"Foo",
+		"[A]x",
+		"[a-c]y",
+		"[[:upper:]]z",
+		"[[:lower:]]w",
+		"*.TXT",
+		"/Dir/Sub",
+		"[B-D]v",
+		"\\Qu",
+		"[!A-C]n",
+	}, WithCaseFold())
+
+	assert.NoError(t, err)
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	type TestCase struct {
+		path     string
+		expected bool
+	}
+
+	tests := []TestCase{
+		{"foo", true},
+		{"FOO", true},
+		{"Ax", false}, // git compares letters in character classes without folding them
+		{"ax", false},
+		{"Ay", true},
+		{"ay", true},
+		{"By", true},
+		{"Bz", true},
+		{"bz", true},
+		{"Aw", true},
+		{"aw", true},
+		{"A.txt", true},
+		{"a.TxT", true},
+		{"dir/sub", true},
+		{"DIR/SUB/x", true},
+		{"Bv", true},
+		{"bv", true},
+		{"Ev", false},
+		{"Qu", false}, // same for escaped letters
+		{"qu", false},
+		{"an", false},
+		{"Bn", false},
+		{"dn", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ignoreObject.MatchesPath(test.path), test.path)
+	}
+
+	// Without the option, nothing is folded
+	ignoreObject, err = Compile([]string{"Foo", "[A]x"})
+	assert.NoError(t, err)
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo"), "foo should not match")
+	assert.Equal(t, true, ignoreObject.MatchesPath("Ax"), "Ax should match")
+}
+
+func TestCompile_Strict(t *testing.T) {
+	ignoreObject, err := Compile([]string{"ok", "[a-z"}, WithStrict())
+	assert.Nil(t, ignoreObject, "Returned object should be nil")
+	assert.EqualError(t, err, ":2:1: unclosed character class: [a-z")
+
+	ignoreObject, err = Compile([]string{"ok", "[a-z"})
+	assert.NoError(t, err)
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Len(t, ignoreObject.Warnings(), 1)
+}
+
+func TestCompile_BaseDir(t *testing.T) {
+	ignoreObject, err := Compile([]string{"/build", "foo/bar", "*.o", "!keep.o"}, WithBaseDir("src/lib"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("src/lib/build"), "src/lib/build should match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("src/lib/x/build"), "src/lib/x/build should not match")
+	assert.Equal(t, true, ignoreObject.MatchesPath("src/lib/foo/bar"), "src/lib/foo/bar should match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("src/lib/a/foo/bar"), "src/lib/a/foo/bar should not match")
+	assert.Equal(t, true, ignoreObject.MatchesPath("src/lib/a.o"), "src/lib/a.o should match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("src/lib/keep.o"), "src/lib/keep.o should not match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build"), "build should not match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a.o"), "a.o should not match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("src/a.o"), "src/a.o should not match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("src/lib"), "the base directory itself should not match")
+	assert.Equal(t, ":1:/build", ignoreObject.Explain("src/lib/build/x").String())
+	assert.Nil(t, ignoreObject.Explain("build"), "build should not match any rule")
+
+	assert.Equal(t, true, ignoreObject.AppliesTo("src/lib/a.o"), "should apply to src/lib/a.o")
+	assert.Equal(t, true, ignoreObject.AppliesTo("./src/lib/x/"), "should apply to src/lib/x/")
+	assert.Equal(t, false, ignoreObject.AppliesTo("src/lib"), "should not apply to src/lib")
+	assert.Equal(t, false, ignoreObject.AppliesTo("src/library/a.o"), "should not apply to src/library/a.o")
+	assert.Equal(t, false, ignoreObject.AppliesTo("../a.o"), "should not apply to ../a.o")
+
+	assert.Equal(t, true, CompileIgnoreLines("a").AppliesTo("a"), "should apply to everything without a base directory")
+
+	ignoreObject, err = Compile([]string{"*"}, WithBaseDir("../outside"))
+	assert.NoError(t, err)
+	assert.Equal(t, false, ignoreObject.MatchesPath("a"), "an invalid base directory should match nothing")
+	assert.Equal(t, false, ignoreObject.AppliesTo("../outside/a"), "an invalid base directory should apply to nothing")
+
+	ignoreObject, err = Compile([]string{"Foo"}, WithBaseDir("Src"), WithCaseFold())
+	assert.NoError(t, err)
+	assert.Equal(t, true, ignoreObject.MatchesPath("SRC/foo"), "SRC/foo should match")
+	assert.Equal(t, true, ignoreObject.AppliesTo("src/a"), "should apply to src/a")
 }
 
 func TestUnclosedCharacterClass(t *testing.T) {
@@ -461,6 +772,51 @@ func TestUnclosedCharacterClass(t *testing.T) {
 	assert.Equal(t, true, ignoreObject.MatchesPath("a[A-Z-files"), "should match a[A-Z-files")
 }
 
+func TestCompileIgnoreLinesStrict(t *testing.T) {
+	ignoreObject, err := CompileIgnoreLinesStrict("*.o", "!/foo/[a-z", "bar")
+	assert.Nil(t, ignoreObject, "Returned object should be nil")
+
+	var patternErr *PatternError
+	assert.ErrorAs(t, err, &patternErr)
+	assert.Equal(t, &PatternError{Line: 2, Column: 7, Pattern: "!/foo/[a-z", Reason: "unclosed character class"}, patternErr)
+	assert.Equal(t, ":2:7: unclosed character class: !/foo/[a-z", err.Error())
+
+	ignoreObject, err = CompileIgnoreLinesStrict("*.o", "[[:alpha:]]", "\\[hello", "[]-]")
+	assert.NoError(t, err)
+	if !assert.NotNil(t, ignoreObject, "Returned object should not be nil") {
+		return
+	}
+	assert.Empty(t, ignoreObject.Warnings())
+}
+
+func TestWarnings(t *testing.T) {
+	ignoreObject := CompileIgnoreLines(
+		"a//b[c/d[",
+		"# comment [",
+		"ok",
+		"foo\\",
+		"foo\\\\",
+		"[[:digit:]",
+	)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, []*PatternError{
+		{Line: 1, Column: 5, Pattern: "a//b[c/d[", Reason: "unclosed character class"},
+		{Line: 1, Column: 9, Pattern: "a//b[c/d[", Reason: "unclosed character class"},
+		{Line: 4, Column: 4, Pattern: "foo\\", Reason: "trailing backslash"},
+		{Line: 6, Column: 1, Pattern: "[[:digit:]", Reason: "unclosed character class"},
+	}, ignoreObject.Warnings())
+
+	// Malformed patterns never match, like in git
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/b["), "a/b[ should not match")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo\\"), "foo\\ should match the escaped backslash")
+	assert.Equal(t, true, ignoreObject.MatchesPath("ok"), "ok should match")
+
+	ignoreObject = CompileIgnoreLines("foo\\")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo\\"), "foo\\ should not match")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo"), "foo should not match")
+}
+
 func TestStarExponentialBehaviour(t *testing.T) {
 	gitIgnore := []string{"*a*a*a*a*a*a*a*a*a*a*a*a"}
 	ignoreObject := CompileIgnoreLines(gitIgnore...)
@@ -525,6 +881,10 @@ func TestMySplit(t *testing.T) {
 	for _, test := range tests {
 		result := mySplit(test.str, test.separator)
 		assert.Equal(t, test.expected, result)
+
+		var buf [2]string
+		result = mySplitBuf(test.str, test.separator, buf[:0])
+		assert.Equal(t, test.expected, result)
 	}
 }
 
@@ -532,11 +892,13 @@ func FuzzMatchComponent(f *testing.F) {
 	f.Add("hello, world!", "hell*[oasd], [[:alpha:]]orld!")
 	f.Add("hello, world!", "hell*[!asd], [![:digit:]]orld!")
 	f.Fuzz(func(t *testing.T, str string, pattern string) {
-		comp, err := makeRuleComponent(pattern)
-		if err != nil {
-			return
+		for _, caseFold := range []bool{false, true} {
+			comp, err := makeRuleComponent(pattern, caseFold)
+			if err != nil {
+				return
+			}
+			matchComponent(str, comp)
 		}
-		matchComponent(str, comp)
 	})
 }
 
@@ -603,8 +965,11 @@ func FuzzCorrectness(f *testing.F) {
 			return "false"
 		}
 
-		// Split in the same way that CompileIgnoreFile does
-		ignoreObject := CompileIgnoreLines(strings.Split(gitIgnoreContent, "\n")...)
+		// Read in the same way that CompileIgnoreFile does, with the byte order mark skipped
+		ignoreObject, err := CompileIgnoreReader(strings.NewReader(gitIgnoreContent))
+		if !assert.NoError(t, err) {
+			return
+		}
 		result := ignoreObject.MatchesPath(path)
 
 		if result != expected {
@@ -623,3 +988,244 @@ func FuzzCorrectness(f *testing.F) {
 		}
 	})
 }
+
+func TestClassifyRule(t *testing.T) {
+	tests := []struct {
+		pattern string
+		kind    ruleKind
+		literal string
+	}{
+		{"node_modules", nameRule, "node_modules"},
+		{"node_modules/", nameRule, "node_modules"},
+		{"!\\#notes", nameRule, "#notes"},
+		{"*.o", extensionRule, ".o"},
+		{"*.tar.gz", extensionRule, ".tar.gz"},
+		{"!*.log/", extensionRule, ".log"},
+		{"/build", anchoredNameRule, "build"},
+		{"/build/", anchoredNameRule, "build"},
+		{"*", generalRule, ""},
+		{"**", generalRule, ""},
+		{"*~", generalRule, ""},
+		{"*.[oa]", generalRule, ""},
+		{"/*.c", generalRule, ""},
+		{"a/b", generalRule, ""},
+		{"foo*", generalRule, ""},
+		{"foo\\", generalRule, ""},
+		{"[a-z", generalRule, ""},
+	}
+
+	for _, test := range tests {
+		r, _ := createRule(test.pattern, false)
+		assert.Equal(t, test.kind, r.Kind, "kind of %q", test.pattern)
+		assert.Equal(t, test.literal, r.Literal, "literal of %q", test.pattern)
+	}
+
+	r, _ := createRule("*.O", true)
+	assert.Equal(t, extensionRule, r.Kind)
+	assert.Equal(t, ".o", r.Literal, "the literal should be folded")
+}
+
+// The fast paths must agree with interpreting the components
+func TestRuleFastPaths(t *testing.T) {
+	patterns := []string{"node_modules", "build/", "*.o", "*.tar.gz", "*.d/", "/build", "/out/", ".o"}
+	paths := []string{
+		"node_modules", "a/node_modules", "node_modules/a", "node_modules2",
+		"build", "a/build", "build/a", "a/build/b",
+		"a.o", ".o", "a.o/b", "b/a.o", "a.oo", "o",
+		"x.tar.gz", "x.gz", "a/x.tar.gz/y",
+		"x.d", "a/x.d", "x.d/y",
+		"out", "out/a", "a/out",
+	}
+
+	for _, pattern := range patterns {
+		fast, _ := createRule(pattern, false)
+		assert.NotEqual(t, generalRule, fast.Kind, "%q should have a fast path", pattern)
+		interpreted := fast
+		interpreted.Kind = generalRule
+
+		for _, path := range paths {
+			for _, isDir := range []bool{false, true} {
+				pathComponents := mySplit(path, '/')
+				assert.Equal(t, interpreted.matchesPath(isDir, pathComponents), fast.matchesPath(isDir, pathComponents), "%q against %q, isDir %v", pattern, path, isDir)
+			}
+		}
+	}
+}
+
+// Based on the Node, Go and Python templates of github/gitignore
+const realisticIgnoreFile = `# Logs
+logs
+*.log
+npm-debug.log*
+yarn-debug.log*
+yarn-error.log*
+
+# Runtime data
+pids
+*.pid
+*.seed
+*.pid.lock
+
+# Coverage
+lib-cov
+coverage
+*.lcov
+.nyc_output
+
+# Dependencies
+node_modules/
+jspm_packages/
+bower_components
+/vendor/
+
+# Build output
+/build
+/dist
+out/
+*.tsbuildinfo
+.cache/
+.next
+.nuxt
+
+# Go
+*.exe
+*.exe~
+*.dll
+*.so
+*.dylib
+*.test
+*.out
+go.work
+
+# Python
+__pycache__/
+*.py[cod]
+*$py.class
+*.egg-info/
+.eggs/
+.venv
+venv/
+.pytest_cache/
+.mypy_cache/
+
+# Editors
+.idea/
+.vscode/*
+!.vscode/settings.json
+!.vscode/extensions.json
+*.swp
+*~
+.DS_Store
+Thumbs.db
+
+# Environment
+.env
+.env.*
+!.env.example
+`
+
+func BenchmarkRealisticIgnoreFile(b *testing.B) {
+	lines := strings.Split(realisticIgnoreFile, "\n")
+	paths := benchmarkListing()
+	paths = append(paths, "node_modules/react/index.js", "web/src/app.ts", "web/.vscode/settings.json", "api/__pycache__/x.pyc", "build/out.o")
+
+	interpreted := CompileIgnoreLines(lines...)
+	for i := range interpreted.rules {
+		interpreted.rules[i].Kind = generalRule
+	}
+	fast := CompileIgnoreLines(lines...)
+	indexed, _ := Compile(lines, WithIndex())
+
+	for _, benchmark := range []struct {
+		name   string
+		ignore *GitIgnore
+	}{
+		{"interpreted", interpreted},
+		{"fast paths", fast},
+		{"indexed", indexed},
+	} {
+		b.Run(benchmark.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, path := range paths {
+					benchmark.ignore.MatchesPath(path)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesCleanPath(t *testing.T) {
+	ignoreObject := CompileIgnoreLines("*.o", "!keep.o", "build/", "/docs/*.md")
+
+	for _, path := range []string{".", "a.o", "keep.o", "src/keep.o", "build", "build/x", "a/build/keep.o", "docs/a.md", "a/docs/a.md"} {
+		for _, isDir := range []bool{false, true} {
+			expected := ignoreObject.MatchesPathIsDir(path, isDir)
+			assert.Equal(t, expected, ignoreObject.MatchesCleanPath(path, isDir), "%q, isDir %v", path, isDir)
+			assert.Equal(t, expected, ignoreObject.MatchesComponents(splitCleanPath(path), isDir), "%q, isDir %v", path, isDir)
+		}
+	}
+}
+
+func TestMatchesComponents_CaseFold(t *testing.T) {
+	ignoreObject, err := Compile([]string{"Build/"}, WithCaseFold())
+	assert.NoError(t, err)
+
+	pathComponents := []string{"SRC", "BUILD", "x"}
+	assert.Equal(t, true, ignoreObject.MatchesComponents(pathComponents, false), "SRC/BUILD/x should match")
+	assert.Equal(t, []string{"SRC", "BUILD", "x"}, pathComponents, "the components should not be modified")
+	assert.Equal(t, true, ignoreObject.MatchesCleanPath("SRC/BUILD/x", false), "SRC/BUILD/x should match")
+}
+
+// The hot path must not allocate, whatever options the GitIgnore was compiled with
+func TestMatchesCleanPath_Allocations(t *testing.T) {
+	lines := strings.Split(realisticIgnoreFile, "\n")
+	pathComponents := []string{"services", "api", "internal", "handlers", "user.go"}
+	path := strings.Join(pathComponents, "/")
+
+	for _, opts := range [][]Option{
+		{},
+		{WithIndex()},
+		{WithDirCache(16)},
+		{WithCaseFold()},
+		{WithBaseDir("services")},
+		{WithIndex(), WithDirCache(16), WithCaseFold()},
+	} {
+		ignoreObject, err := Compile(lines, opts...)
+		assert.NoError(t, err)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			ignoreObject.MatchesCleanPath(path, false)
+		})
+		assert.Equal(t, 0.0, allocs, "MatchesCleanPath with %d options", len(opts))
+
+		allocs = testing.AllocsPerRun(100, func() {
+			ignoreObject.MatchesComponents(pathComponents, false)
+		})
+		assert.Equal(t, 0.0, allocs, "MatchesComponents with %d options", len(opts))
+	}
+}
+
+func BenchmarkMatchesCleanPath(b *testing.B) {
+	ignoreObject := CompileIgnoreLines(strings.Split(realisticIgnoreFile, "\n")...)
+	path := "services/api/internal/handlers/user.go"
+	pathComponents := strings.Split(path, "/")
+
+	b.Run("MatchesPath", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ignoreObject.MatchesPath(path)
+		}
+	})
+	b.Run("MatchesCleanPath", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ignoreObject.MatchesCleanPath(path, false)
+		}
+	})
+	b.Run("MatchesComponents", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ignoreObject.MatchesComponents(pathComponents, false)
+		}
+	})
+}
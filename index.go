@@ -0,0 +1,108 @@
+package goignore
+
+import (
+	"slices"
+)
+
+// Buckets the rules by literal parts a path must contain for them to match, see WithIndex()
+// Only the rules in the buckets of a path have to be tried, so matching mostly doesn't depend on the number of rules
+// The buckets hold indices into the rules of the GitIgnore, in increasing order
+type ruleIndex struct {
+	names    map[string][]int // unanchored rules matching a literal name, like "node_modules"
+	suffixes map[string][]int // unanchored rules like "*.o", keyed by the suffix starting at a '.'
+	anchored indexNode        // anchored rules, keyed by their leading literal components
+	rest     []int            // everything else, these are always tried
+}
+
+// A node of the trie of anchored rules
+type indexNode struct {
+	rules    []int // the anchored rules whose leading literal components lead here
+	children map[string]*indexNode
+}
+
+func newRuleIndex() *ruleIndex {
+	return &ruleIndex{
+		names:    make(map[string][]int),
+		suffixes: make(map[string][]int),
+	}
+}
+
+// Adds the rule with index i to the buckets, rules have to be added in increasing order
+func (idx *ruleIndex) add(r *rule, i int) {
+	// unanchored rules are matched against every component of the path
+	switch r.Kind {
+	case nameRule:
+		idx.names[r.Literal] = append(idx.names[r.Literal], i)
+		return
+	case extensionRule:
+		idx.suffixes[r.Literal] = append(idx.suffixes[r.Literal], i)
+		return
+	}
+
+	if !r.Relative {
+		idx.rest = append(idx.rest, i)
+		return
+	}
+
+	// anchored rules only match paths starting with the same literal components
+	node := &idx.anchored
+	for j := range r.Components {
+		name, ok := literalComponent(&r.Components[j])
+		if !ok {
+			break
+		}
+		child, ok := node.children[name]
+		if !ok {
+			child = &indexNode{}
+			if node.children == nil {
+				node.children = make(map[string]*indexNode)
+			}
+			node.children[name] = child
+		}
+		node = child
+	}
+	node.rules = append(node.rules, i)
+}
+
+// Same as GitIgnore.lastMatchingRule, but only tries the rules in the buckets of the path
+func (idx *ruleIndex) lastMatchingRule(rules []rule, isDir bool, pathComponents []string) *rule {
+	var buffer [32]int
+	candidates := append(buffer[:0], idx.anchored.rules...)
+
+	node := &idx.anchored
+	for _, component := range pathComponents {
+		candidates = append(candidates, idx.names[component]...)
+		for i := 0; i < len(component); i++ {
+			if component[i] == '.' {
+				candidates = append(candidates, idx.suffixes[component[i:]]...)
+			}
+		}
+		if node != nil {
+			node = node.children[component]
+			if node != nil {
+				candidates = append(candidates, node.rules...)
+			}
+		}
+	}
+	slices.Sort(candidates)
+
+	// try the candidates and the rest from the last rule to the first, the same rule may be a candidate more than once
+	i, j := len(candidates)-1, len(idx.rest)-1
+	for i >= 0 || j >= 0 {
+		var next int
+		if j < 0 || (i >= 0 && candidates[i] > idx.rest[j]) {
+			next = candidates[i]
+			for i >= 0 && candidates[i] == next {
+				i--
+			}
+		} else {
+			next = idx.rest[j]
+			j--
+		}
+
+		if rules[next].matchesPath(isDir, pathComponents) {
+			return &rules[next]
+		}
+	}
+	return nil
+}
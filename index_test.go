@@ -0,0 +1,163 @@
+package goignore
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The index must pick the same rule as trying every rule does
+func assertSameAsIndexed(t *testing.T, lines []string, paths []string, opts ...Option) {
+	plain, err := Compile(lines, opts...)
+	assert.NoError(t, err)
+	indexed, err := Compile(lines, append(opts, WithIndex())...)
+	assert.NoError(t, err)
+
+	for _, path := range paths {
+		for _, isDir := range []bool{false, true} {
+			pathComponents, _, ok := splitPath(path)
+			if !ok {
+				continue
+			}
+			expected := plain.decidingRule(append([]string{}, pathComponents...), isDir).toMatch()
+			actual := indexed.decidingRule(pathComponents, isDir).toMatch()
+			assert.Equal(t, expected, actual, "%q for %q, isDir %v", lines, path, isDir)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	lines := []string{
+		"node_modules",
+		"*.o",
+		"!keep.o",
+		"*.tar.gz",
+		"/build",
+		"/docs/*.md",
+		"!/docs/README.md",
+		"src/gen/",
+		"**/testdata",
+		"foo*",
+		"*.O",
+		"[a-c].txt",
+		"/a/b/c",
+		"!a",
+		"\\*.o",
+		"x\\",
+		"*",
+		"!*/",
+		".o",
+	}
+	paths := []string{
+		"node_modules", "a/node_modules/x", "node_modules/",
+		"a.o", "keep.o", "src/keep.o", "x.o.c", ".o", "a.O",
+		"x.tar.gz", "x.gz", "a/b.tar.gz",
+		"build", "build/x", "a/build",
+		"docs/a.md", "docs/README.md", "docs/x/a.md", "a/docs/a.md",
+		"src/gen", "src/gen/x", "a/src/gen",
+		"testdata", "a/b/testdata/c",
+		"foobar", "a/foo",
+		"a.txt", "d.txt",
+		"a/b/c", "a/b/c/d", "a/b", "a",
+		"*.o", "x", "x\\",
+	}
+
+	// every prefix of the list, so each rule gets to be the last one
+	for n := 0; n <= len(lines); n++ {
+		assertSameAsIndexed(t, lines[:n], paths)
+		assertSameAsIndexed(t, lines[:n], paths, WithCaseFold())
+	}
+}
+
+func TestIndex_Random(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	names := []string{"a", "b", "a.o", "b.c", "x.tar.gz", "build", "docs"}
+	patterns := []string{"a", "b", "*.o", "*.c", "*.gz", "*.tar.gz", "build", "docs", "*", "**", "a*", "[ab]", "?.o"}
+
+	randomPath := func(parts []string) string {
+		n := 1 + random.Intn(4)
+		elements := make([]string, n)
+		for i := range elements {
+			elements[i] = parts[random.Intn(len(parts))]
+		}
+		return strings.Join(elements, "/")
+	}
+
+	for round := 0; round < 200; round++ {
+		lines := make([]string, 1+random.Intn(10))
+		for i := range lines {
+			line := randomPath(patterns)
+			if random.Intn(3) == 0 {
+				line = "/" + line
+			}
+			if random.Intn(4) == 0 {
+				line += "/"
+			}
+			if random.Intn(3) == 0 {
+				line = "!" + line
+			}
+			lines[i] = line
+		}
+
+		paths := make([]string, 20)
+		for i := range paths {
+			paths[i] = randomPath(names)
+		}
+		assertSameAsIndexed(t, lines, paths)
+	}
+}
+
+func FuzzIndex(f *testing.F) {
+	f.Add("*.o\n!keep.o\n/build\ndocs/*.md", "docs/a.md")
+	f.Add("node_modules\n*.tar.gz", "a/node_modules/b.tar.gz")
+	f.Fuzz(func(t *testing.T, ignore string, path string) {
+		lines := strings.Split(ignore, "\n")
+		plain := CompileIgnoreLines(lines...)
+		indexed, _ := Compile(lines, WithIndex())
+
+		assert.Equal(t, plain.Explain(path), indexed.Explain(path), "%q for %q", lines, path)
+	})
+}
+
+// An ignore file like the ones generated by monorepo tooling
+func generatedIgnoreLines(n int) []string {
+	lines := make([]string, 0, n)
+	for i := 0; len(lines) < n; i++ {
+		number := strconv.Itoa(i)
+		lines = append(lines,
+			"/services/service"+number+"/build/",
+			"/services/service"+number+"/*.generated.go",
+			"*.ext"+number,
+			"cache"+number,
+		)
+	}
+	return lines[:n]
+}
+
+func BenchmarkIndex(b *testing.B) {
+	paths := benchmarkListing()
+
+	for _, n := range []int{10, 100, 1000} {
+		lines := generatedIgnoreLines(n)
+		plain := CompileIgnoreLines(lines...)
+		indexed, _ := Compile(lines, WithIndex())
+
+		b.Run("rules="+strconv.Itoa(n)+"/plain", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, path := range paths {
+					plain.MatchesPath(path)
+				}
+			}
+		})
+		b.Run("rules="+strconv.Itoa(n)+"/indexed", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, path := range paths {
+					indexed.MatchesPath(path)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package goignore
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The kinds of problems Lint() finds
+type LintCheck string
+
+const (
+	// The rule never decides anything, a later rule matches every path it does
+	LintShadowed LintCheck = "shadowed"
+	// The same rule is repeated later, which makes this one useless
+	LintDuplicate LintCheck = "duplicate"
+	// The negation can't re-include anything, because a parent directory of the paths it matches is excluded
+	LintIneffectiveNegation LintCheck = "ineffective-negation"
+	// A '[' is never closed, the rule never matches
+	LintUnclosedClass LintCheck = "unclosed-class"
+	// Trailing spaces were removed from the line, or there is trailing whitespace which is kept
+	LintTrailingWhitespace LintCheck = "trailing-whitespace"
+	// The rule can't match any path, e.g. it has a "." or ".." component
+	LintNeverMatches LintCheck = "never-matches"
+)
+
+// Describes a problem in an ignore file found by Lint()
+// Line and Column are 1-based, Column is 1 for problems with the whole rule
+// Related is the other rule involved, e.g. the one which shadows this one, it is nil if there is none
+type Finding struct {
+	Check   LintCheck
+	Source  string
+	Line    int
+	Column  int
+	Pattern string
+	Message string
+	Related *Match
+}
+
+// Formats the finding like PatternError, e.g. ".gitignore:3:1: shadowed by line 7 (*.o): foo.o"
+func (f *Finding) String() string {
+	return f.Source + ":" + strconv.Itoa(f.Line) + ":" + strconv.Itoa(f.Column) + ": " + f.Message + ": " + f.Pattern
+}
+
+// Looks for rules which don't do what they seem to, see LintCheck for the problems it finds
+// The findings are sorted by line, shadowing is only found in simple cases, so it misses some
+func (g *GitIgnore) Lint() []*Finding {
+	return g.lint(func(dir []string) *rule {
+		return g.lastMatchingRule(true, dir)
+	})
+}
+
+// Same as GitIgnore.Lint(), for each layer in order
+// Negations are checked against the directories excluded by every layer,
+// but shadowed and duplicate rules are only found within a layer
+func (s *Stack) Lint() []*Finding {
+	var findings []*Finding
+	for _, layer := range s.layers {
+		findings = append(findings, layer.lint(func(dir []string) *rule {
+			path := append(append([]string{}, layer.base...), dir...)
+			return s.lastMatchingRule(true, path, s.fold(path))
+		})...)
+	}
+	return findings
+}
+
+// Same as GitIgnore.Lint(), for every ignore file of the repository
+func (r *Repository) Lint() []*Finding {
+	return r.stack.Lint()
+}
+
+// excludedBy returns the rule which decides about the directory, relative to the base directory
+func (g *GitIgnore) lint(excludedBy func(dir []string) *rule) []*Finding {
+	var findings []*Finding
+	add := func(r *rule, check LintCheck, column int, message string, related *rule) {
+		findings = append(findings, &Finding{
+			Check:   check,
+			Source:  r.Source,
+			Line:    r.Line,
+			Column:  column,
+			Pattern: r.Pattern,
+			Message: message,
+			Related: related.toMatch(),
+		})
+	}
+
+	for _, warning := range g.warnings {
+		check := LintNeverMatches
+		if warning.Reason == "unclosed character class" {
+			check = LintUnclosedClass
+		}
+		findings = append(findings, &Finding{
+			Check:   check,
+			Source:  warning.Source,
+			Line:    warning.Line,
+			Column:  warning.Column,
+			Pattern: warning.Pattern,
+			Message: warning.Reason + ", the rule never matches",
+		})
+	}
+
+	for i := range g.rules {
+		r := &g.rules[i]
+
+		if r.TrimmedSpaces {
+			add(r, LintTrailingWhitespace, len(r.Pattern)+1, "trailing spaces are removed, escape them with a backslash to keep them", nil)
+		} else if strings.ContainsAny(r.Pattern[len(r.Pattern)-1:], "\t\v\f\r") {
+			add(r, LintTrailingWhitespace, len(r.Pattern), "trailing whitespace other than spaces is part of the pattern", nil)
+		}
+
+		if column, ok := dotComponentColumn(r.Pattern); ok {
+			add(r, LintNeverMatches, column, "paths never have \".\" or \"..\" components, the rule never matches", nil)
+			continue
+		}
+		if hasEmptyClass(r) {
+			add(r, LintNeverMatches, 1, "a character class matches no characters, the rule never matches", nil)
+			continue
+		}
+		if neverMatches(r) {
+			// already reported from the warnings
+			continue
+		}
+
+		if later := g.laterCoveringRule(i); later != nil {
+			if sameRule(r, later) {
+				add(r, LintDuplicate, 1, "duplicate of "+describeRule(later, r.Source)+", only the last one takes effect", later)
+			} else {
+				add(r, LintShadowed, 1, "shadowed by "+describeRule(later, r.Source), later)
+			}
+		}
+
+		if r.Negate {
+			if dir, excluding := excludedParent(r, excludedBy); excluding != nil {
+				add(r, LintIneffectiveNegation, 1, "can't re-include anything, the parent directory "+dir+"/ is excluded by "+describeRule(excluding, r.Source), excluding)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// Refers to a rule in a message, the source is left out if it is the same as the one of the finding
+func describeRule(r *rule, source string) string {
+	where := "line " + strconv.Itoa(r.Line)
+	if r.Source != source {
+		where = r.Source + ":" + strconv.Itoa(r.Line)
+	}
+	return where + " (" + r.Pattern + ")"
+}
+
+// Returns the 1-based column of the first "." or ".." component of the pattern
+// git never normalizes patterns, and paths don't have such components, so they never match
+func dotComponentColumn(pattern string) (int, bool) {
+	offset := 0
+	if strings.HasPrefix(pattern, "!") {
+		offset++
+	}
+	for offset < len(pattern) {
+		end := strings.IndexByte(pattern[offset:], '/')
+		if end == -1 {
+			end = len(pattern) - offset
+		}
+		if component := pattern[offset : offset+end]; component == "." || component == ".." {
+			return offset + 1, true
+		}
+		offset += end + 1
+	}
+	return 0, false
+}
+
+// Reports whether the rule has a character class which no byte matches, like an escaped
+// uppercase letter with WithCaseFold()
+func hasEmptyClass(r *rule) bool {
+	for _, component := range r.Components {
+		for _, instruction := range component.Instructions {
+			if instruction.Type == charClass && strings.Trim(instruction.Pattern, "\x00") == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reports whether a component of the rule was left empty because it is malformed, see createRule()
+func neverMatches(r *rule) bool {
+	for _, component := range r.Components {
+		if !component.Star && !component.Starstar && len(component.Instructions) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the first rule after the i-th one which matches every path that one does
+func (g *GitIgnore) laterCoveringRule(i int) *rule {
+	for j := i + 1; j < len(g.rules); j++ {
+		later := &g.rules[j]
+		if !neverMatches(later) && covers(later, &g.rules[i]) {
+			return later
+		}
+	}
+	return nil
+}
+
+// Reports whether the two rules match the same paths in the same way
+func sameRule(a *rule, b *rule) bool {
+	if a.Negate != b.Negate || a.OnlyDirectory != b.OnlyDirectory || a.Relative != b.Relative || len(a.Components) != len(b.Components) {
+		return false
+	}
+	for i := range a.Components {
+		if !sameComponent(&a.Components[i], &b.Components[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameComponent(a *ruleComponent, b *ruleComponent) bool {
+	if a.Star != b.Star || a.Starstar != b.Starstar || len(a.Instructions) != len(b.Instructions) {
+		return false
+	}
+	for i := range a.Instructions {
+		if a.Instructions[i] != b.Instructions[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether the later rule matches every path the earlier one does
+// Only simple cases are recognized, it returns false when in doubt
+func covers(later *rule, earlier *rule) bool {
+	components := later.Components
+	relative := later.Relative
+	// "**/foo" is the same as "foo", but "**/foo/" isn't the same as "foo/", see equivalentRules()
+	if len(components) == 2 && components[0].Starstar && !components[1].Starstar && !later.OnlyDirectory {
+		components = components[1:]
+		relative = false
+	}
+
+	// matters when the paths matched by the earlier rule end with the component the later one matches
+	dirOK := !later.OnlyDirectory || earlier.OnlyDirectory
+
+	if !relative {
+		// matches the first component of the path matching it, wherever it is
+		last := len(earlier.Components) - 1
+		for i := range earlier.Components {
+			if !earlier.Relative && i > 0 {
+				break
+			}
+			if componentCovers(&components[0], &earlier.Components[i]) && (i < last || dirOK) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// matches the paths starting with its components, and everything below them
+	if !earlier.Relative || len(earlier.Components) < len(components) {
+		return false
+	}
+	for i := range components {
+		if components[i].Starstar || earlier.Components[i].Starstar || !componentCovers(&components[i], &earlier.Components[i]) {
+			return false
+		}
+	}
+	return len(earlier.Components) > len(components) || dirOK
+}
+
+// Reports whether the later component matches every name the earlier one does
+func componentCovers(later *ruleComponent, earlier *ruleComponent) bool {
+	switch {
+	case later.Starstar:
+		return true
+	case earlier.Starstar:
+		return false
+	case later.Star:
+		return true
+	case sameComponent(later, earlier):
+		return true
+	}
+
+	// "*suffix" matches the names ending with the suffix, and the patterns ending with it
+	if len(later.Instructions) != 2 || later.Instructions[0].Type != star || later.Instructions[1].Type != raw {
+		return false
+	}
+	suffix := later.Instructions[1].Pattern
+	if name, ok := literalComponent(earlier); ok {
+		return strings.HasSuffix(name, suffix)
+	}
+	instructions := earlier.Instructions
+	return len(instructions) == 2 && instructions[0].Type == star && instructions[1].Type == raw &&
+		strings.HasSuffix(instructions[1].Pattern, suffix)
+}
+
+// Finds the excluded parent directory of the paths the negated rule matches
+// Only the leading components without wildcards are checked, the other parents depend on the path
+func excludedParent(r *rule, excludedBy func(dir []string) *rule) (string, *rule) {
+	if !r.Relative {
+		return "", nil
+	}
+
+	var dir []string
+	for i := 0; i < len(r.Components)-1; i++ {
+		name, ok := literalComponent(&r.Components[i])
+		if !ok {
+			break
+		}
+		dir = append(dir, name)
+		if excluding := excludedBy(dir); excluding != nil && !excluding.Negate {
+			return strings.Join(dir, "/"), excluding
+		}
+	}
+	return "", nil
+}
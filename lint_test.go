@@ -0,0 +1,169 @@
+package goignore
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the check and the line of each finding, like "shadowed:3"
+func lintSummary(findings []*Finding) []string {
+	summary := []string{}
+	for _, finding := range findings {
+		summary = append(summary, string(finding.Check)+":"+strconv.Itoa(finding.Line))
+	}
+	return summary
+}
+
+func TestLint(t *testing.T) {
+	for _, test := range []struct {
+		lines    []string
+		expected []string
+	}{
+		{[]string{"*.o", "build/", "!keep.o", "/vendor", "docs/*.html"}, []string{}},
+
+		// shadowed and duplicate rules
+		{[]string{"a.o", "*.o"}, []string{"shadowed:1"}},
+		{[]string{"*.o", "a.o"}, []string{}},
+		{[]string{"*.tar.gz", "*.gz"}, []string{"shadowed:1"}},
+		{[]string{"/top/x", "top"}, []string{"shadowed:1"}},
+		{[]string{"/a/b/", "/a/*"}, []string{"shadowed:1"}},
+		{[]string{"/a/b", "/a/b/"}, []string{}},
+		{[]string{"name", "**/name"}, []string{"shadowed:1"}},
+		{[]string{"anything/**/x", "*"}, []string{"shadowed:1"}},
+		{[]string{"x", "!x"}, []string{"shadowed:1"}},
+		{[]string{"x/", "x"}, []string{"shadowed:1"}},
+		{[]string{"x", "x/"}, []string{}},
+		{[]string{"dup", "!dup", "dup"}, []string{"shadowed:1", "shadowed:2"}},
+		{[]string{"dup", "other", "\\dup"}, []string{"duplicate:1"}},
+
+		// negations
+		{[]string{"folder", "!folder/subfolder"}, []string{"ineffective-negation:2"}},
+		{[]string{"!folder/subfolder", "folder/"}, []string{"shadowed:1", "ineffective-negation:1"}},
+		{[]string{"/*", "!/foo", "/foo/*", "!/foo/bar"}, []string{}},
+		{[]string{"build/", "!build/*/keep"}, []string{"ineffective-negation:2"}},
+		{[]string{"build/*", "!build/keep"}, []string{}},
+
+		// malformed and never matching rules
+		{[]string{"[abc", "x\\", "./x", "a/../b", "ok/."}, []string{"unclosed-class:1", "never-matches:2", "never-matches:3", "never-matches:4", "never-matches:5"}},
+		{[]string{"foo  ", "bar\t", "baz\\ "}, []string{"trailing-whitespace:1", "trailing-whitespace:2"}},
+		{[]string{"*.o", "//", "!/", "/", "!"}, []string{"never-matches:2", "never-matches:3", "never-matches:4", "never-matches:5"}},
+	} {
+		assert.Equal(t, test.expected, lintSummary(CompileIgnoreLines(test.lines...).Lint()), test.lines)
+	}
+}
+
+func TestLint_Findings(t *testing.T) {
+	ignore, _ := Compile([]string{"folder", "!folder/subfolder", "[abc", "foo  "}, WithSource(".gitignore"))
+
+	findings := ignore.Lint()
+	assert.Equal(t, []*Finding{
+		{
+			Check:   LintIneffectiveNegation,
+			Source:  ".gitignore",
+			Line:    2,
+			Column:  1,
+			Pattern: "!folder/subfolder",
+			Message: "can't re-include anything, the parent directory folder/ is excluded by line 1 (folder)",
+			Related: &Match{Pattern: "folder", Source: ".gitignore", Line: 1},
+		},
+		{
+			Check:   LintUnclosedClass,
+			Source:  ".gitignore",
+			Line:    3,
+			Column:  1,
+			Pattern: "[abc",
+			Message: "unclosed character class, the rule never matches",
+		},
+		{
+			Check:   LintTrailingWhitespace,
+			Source:  ".gitignore",
+			Line:    4,
+			Column:  4,
+			Pattern: "foo",
+			Message: "trailing spaces are removed, escape them with a backslash to keep them",
+		},
+	}, findings)
+	assert.Equal(t, ".gitignore:2:1: can't re-include anything, the parent directory folder/ is excluded by line 1 (folder): !folder/subfolder", findings[0].String())
+}
+
+func TestLint_OnlySlashes(t *testing.T) {
+	// git skips these lines, so "//" doesn't ignore everything like it seems to
+	ignore, _ := Compile([]string{"*.o", "//"}, WithSource(".gitignore"))
+	findings := ignore.Lint()
+	assert.Equal(t, []string{"never-matches:2"}, lintSummary(findings))
+	assert.Equal(t, ".gitignore:2:1: only '!' and slashes, the rule never matches: //", findings[0].String())
+	assert.EqualError(t, ignore.Warnings()[0], ".gitignore:2:1: only '!' and slashes: //")
+}
+
+func TestLint_CaseFold(t *testing.T) {
+	ignore, _ := Compile([]string{"\\A", "Foo.O", "*.o"}, WithCaseFold())
+	assert.Equal(t, []string{"never-matches:1", "shadowed:2"}, lintSummary(ignore.Lint()))
+}
+
+func TestLint_Repository(t *testing.T) {
+	repo, err := CompileRepositoryFS(fstest.MapFS{
+		".gitignore":     {Data: []byte("build/\n*.o\n")},
+		"sub/.gitignore": {Data: []byte("!build/keep\n!x/y\nz.o\n")},
+	})
+	assert.NoError(t, err)
+
+	findings := repo.Lint()
+	assert.Equal(t, []string{"ineffective-negation:1"}, lintSummary(findings))
+	assert.Equal(t, "sub/.gitignore", findings[0].Source)
+	assert.Equal(t, "can't re-include anything, the parent directory build/ is excluded by .gitignore:1 (build/)", findings[0].Message)
+}
+
+// Paths which exercise the rules in the shadowing tests
+var lintTestPaths = []string{
+	"a", "b", "a/b", "b/a", "a/b/c", "x/a/b", "a/a", "b/b", "x.o", "x.o/a", "x.o/b", "a/x.o/b", "x/x.o/a/b", "name", "x/name",
+}
+
+// Removes the shadowed lines and checks that every path is still matched the same way
+func assertShadowedRemovable(t *testing.T, lines []string, paths []string) {
+	original := CompileIgnoreLines(lines...)
+	for _, finding := range original.Lint() {
+		if finding.Check != LintShadowed && finding.Check != LintDuplicate {
+			continue
+		}
+		removed := append(append([]string{}, lines[:finding.Line-1]...), lines[finding.Line:]...)
+		rewritten := CompileIgnoreLines(removed...)
+		for _, path := range paths {
+			for _, isDir := range []bool{false, true} {
+				assert.Equal(t, original.MatchesPathIsDir(path, isDir), rewritten.MatchesPathIsDir(path, isDir),
+					"%q without line %d, for %q (isDir %v)", lines, finding.Line, path, isDir)
+			}
+		}
+	}
+}
+
+func TestLint_ShadowedRemovable(t *testing.T) {
+	for _, lines := range [][]string{
+		{"a/b", "!**/*/"},
+		{"[ab]/", "!**/[ab]/"},
+		{"x.o/[ab]", "!**/x.o/"},
+		{"name", "**/name"},
+		{"a.o", "*.o"},
+		{"x/", "x"},
+	} {
+		assertShadowedRemovable(t, lines, lintTestPaths)
+	}
+
+	assert.Empty(t, CompileIgnoreLines("a/b", "!**/*/").Lint())
+}
+
+func FuzzLint(f *testing.F) {
+	f.Add("a/b", "!**/*/", "x/a/b")
+	f.Add("[ab]/", "!**/[ab]/", "a/a")
+	f.Add("name", "**/name", "x/name")
+	f.Fuzz(func(t *testing.T, first string, second string, path string) {
+		if strings.ContainsAny(first+second, "\r\n") {
+			// not a single line
+			return
+		}
+		assertShadowedRemovable(t, []string{first, second}, append(lintTestPaths, path))
+	})
+}
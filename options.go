@@ -0,0 +1,92 @@
+package goignore
+
+// Configures how Compile creates a GitIgnore
+type Option func(*compileOptions)
+
+type compileOptions struct {
+	source   string // the name of the file the patterns were read from, only used for reporting
+	baseDir  string
+	caseFold bool
+	strict   bool
+	// the number of directories to remember the verdict of, 0 disables the cache
+	dirCacheSize int
+	index        bool
+}
+
+// Makes the patterns match paths regardless of ASCII case, like git does with core.ignoreCase=true
+func WithCaseFold() Option {
+	return func(options *compileOptions) {
+		options.caseFold = true
+	}
+}
+
+// Makes compiling fail if any of the patterns are malformed, like CompileIgnoreLinesStrict does
+func WithStrict() Option {
+	return func(options *compileOptions) {
+		options.strict = true
+	}
+}
+
+// Binds the patterns to a directory, like the patterns of a .gitignore file in that directory
+// Patterns are matched relative to it, and paths outside of it never match, see AppliesTo()
+// Repositories set the base directory of each file to the directory it is in, and ignore this option
+func WithBaseDir(dir string) Option {
+	return func(options *compileOptions) {
+		options.baseDir = dir
+	}
+}
+
+// Makes the GitIgnore remember whether the last size directories it has seen are ignored
+// Paths in the same directories are matched faster then, since their parents don't have to be matched again
+// The cache is safe for concurrent use, it can be emptied with ClearCache()
+// Repositories ignore this option
+func WithDirCache(size int) Option {
+	return func(options *compileOptions) {
+		options.dirCacheSize = size
+	}
+}
+
+// Indexes the rules by their literal parts, like the names in "node_modules" and "/docs/*.md" or the extension in "*.o"
+// Paths are then only matched against the rules they could match, which is faster for files with many rules
+// The results are the same as without an index, but compiling takes a bit longer
+func WithIndex() Option {
+	return func(options *compileOptions) {
+		options.index = true
+	}
+}
+
+// Sets the name of the file the patterns were read from, it shows up in Explain() and Warnings()
+// Repositories name each file relative to their root, and ignore this option
+func WithSource(source string) Option {
+	return func(options *compileOptions) {
+		options.source = source
+	}
+}
+
+func newCompileOptions(opts []Option) compileOptions {
+	var options compileOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// Creates a GitIgnore from a list of patterns (lines in a .gitignore file), configured by opts
+// Without WithStrict(), the returned error is always nil
+func Compile(lines []string, opts ...Option) (*GitIgnore, error) {
+	return compile(lines, newCompileOptions(opts))
+}
+
+func compile(lines []string, options compileOptions) (*GitIgnore, error) {
+	return checkStrict(compileIgnoreLines(lines, options), options)
+}
+
+// Returns gitignore, unless strict mode is on and some of its patterns are malformed
+func checkStrict(gitignore *GitIgnore, options compileOptions) (*GitIgnore, error) {
+	if options.strict {
+		if err := gitignore.warningsError(); err != nil {
+			return nil, err
+		}
+	}
+	return gitignore, nil
+}
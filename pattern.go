@@ -0,0 +1,310 @@
+package goignore
+
+import (
+	"errors"
+	"strings"
+)
+
+// The parsed form of a pattern, see Parse()
+// Negate is true if the pattern starts with '!'
+// Anchored is true if it only matches relative to the directory of the ignore file,
+// because it starts with a '/' or has more than one segment
+// DirOnly is true if it only matches directories (it ends with '/')
+type Pattern struct {
+	Negate   bool
+	Anchored bool
+	DirOnly  bool
+	Segments []Segment
+}
+
+// A part of a pattern between slashes
+// DoubleStar is true for "**", which matches any number of directories, Nodes is empty then
+type Segment struct {
+	DoubleStar bool
+	Nodes      []Node
+}
+
+// An element of a segment, it is a Literal, Star, Question or Class
+type Node interface {
+	isNode()
+}
+
+// Matches the text itself, escapes are already removed from it
+// With WithCaseFold(), escaped uppercase letters never match, but a Literal can't tell them apart
+type Literal struct {
+	Text string
+}
+
+// Matches any number of characters, "*"
+type Star struct{}
+
+// Matches a single character, "?"
+type Question struct{}
+
+// Matches a single character which is one of the items (or none of them, if Negate is true), like "[a-z_]"
+type Class struct {
+	Negate bool
+	Items  []ClassItem
+}
+
+// A member of a character class
+// It is the range of bytes from Lo to Hi, which are the same for a single character,
+// or a POSIX class like "[:alpha:]" if Name is set, Lo and Hi are unused then
+type ClassItem struct {
+	Lo   byte
+	Hi   byte
+	Name string
+}
+
+func (Literal) isNode()  {}
+func (Star) isNode()     {}
+func (Question) isNode() {}
+func (Class) isNode()    {}
+
+// Returned by Parse() for lines which have no pattern: blank lines, comments, and the ones with only '!' and slashes
+var ErrNoPattern = errors.New("the line has no pattern")
+
+// Parses a line of an ignore file the same way the compiler does
+// Trailing spaces which aren't escaped are removed, like with the other lines
+// The error is a *PatternError for malformed patterns, its Line is always 1
+func Parse(line string) (*Pattern, error) {
+	pattern := beforeFirstNullByte(line)
+	pattern = strings.TrimRight(pattern, "\r\n")
+	pattern = trimUnescapedTrailingSpaces(pattern)
+	if pattern == "" || pattern[0] == '#' || onlyNegationAndSlashes(pattern) {
+		return nil, ErrNoPattern
+	}
+
+	// createRule() finds the malformed parts, so they are the same for both
+	rule, errs := createRule(pattern, false)
+	if len(errs) > 0 {
+		errs[0].Line = 1
+		errs[0].Pattern = pattern
+		return nil, errs[0]
+	}
+
+	if pattern[0] == '!' {
+		pattern = pattern[1:]
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	components := mySplit(pattern, '/')
+	parsed := &Pattern{
+		Negate:   rule.Negate,
+		Anchored: rule.Relative,
+		DirOnly:  rule.OnlyDirectory,
+		Segments: make([]Segment, len(components)),
+	}
+	for i, component := range components {
+		parsed.Segments[i] = parseSegment(component)
+	}
+	return parsed, nil
+}
+
+// Parses a component the same way makeRuleComponent() does, it must not be malformed
+func parseSegment(component string) Segment {
+	switch component {
+	case "*":
+		return Segment{Nodes: []Node{Star{}}}
+	case "**":
+		return Segment{DoubleStar: true}
+	}
+
+	var nodes []Node
+	addLiteral := func(text string) {
+		if n := len(nodes); n > 0 {
+			if literal, ok := nodes[n-1].(Literal); ok {
+				nodes[n-1] = Literal{Text: literal.Text + text}
+				return
+			}
+		}
+		nodes = append(nodes, Literal{Text: text})
+	}
+
+	for r := 0; r < len(component); {
+		switch component[r] {
+		case '*':
+			nodes = append(nodes, Star{})
+			r++
+		case '?':
+			nodes = append(nodes, Question{})
+			r++
+		case '[':
+			var class Class
+			class, r = parseClass(component, r)
+			nodes = append(nodes, class)
+		case '\\':
+			if r+1 < len(component) {
+				addLiteral(component[r+1 : r+2])
+				r += 2
+			} else {
+				// a backslash at the end of a component which isn't the last one is kept
+				addLiteral(component[r:])
+				r++
+			}
+		default:
+			end := r + 1
+			for end < len(component) && strings.IndexByte("*?[\\", component[end]) == -1 {
+				end++
+			}
+			addLiteral(component[r:end])
+			r = end
+		}
+	}
+	return Segment{Nodes: nodes}
+}
+
+// Parses the character class starting at start, and returns the index after it
+func parseClass(component string, start int) (Class, int) {
+	var class Class
+	r := start + 1
+	if component[r] == '!' || component[r] == '^' {
+		class.Negate = true
+		r++
+	}
+	if component[r] == ']' {
+		class.Items = append(class.Items, ClassItem{Lo: ']', Hi: ']'})
+		r++
+	}
+
+	for component[r] != ']' {
+		switch {
+		case component[r] == '\\' && r+1 < len(component):
+			class.Items = append(class.Items, ClassItem{Lo: component[r+1], Hi: component[r+1]})
+			r += 2
+		case r+2 < len(component) && component[r] == '[' && component[r+1] == ':':
+			s := r + 2
+			for component[s] != ']' || component[s-1] != ':' {
+				s++
+			}
+			class.Items = append(class.Items, ClassItem{Name: component[r+2 : s-1]})
+			r = s + 1
+		case r+2 < len(component) && component[r+1] == '-' && component[r+2] != ']':
+			class.Items = append(class.Items, ClassItem{Lo: component[r], Hi: component[r+2]})
+			r += 3
+		default:
+			class.Items = append(class.Items, ClassItem{Lo: component[r], Hi: component[r]})
+			r++
+		}
+	}
+	return class, r + 1
+}
+
+// Turns the pattern back into a line of an ignore file which matches the same paths
+// Special characters are escaped where needed, but the result isn't always the line it was parsed from,
+// e.g. an anchored pattern with more than one segment has no leading '/'
+// Segments must not be empty, and literals must not contain '/'
+func (p *Pattern) String() string {
+	var b strings.Builder
+	switch {
+	case p.Anchored && len(p.Segments) == 1:
+		b.WriteByte('/')
+	case !p.Anchored && len(p.Segments) > 1 && !p.Segments[0].DoubleStar:
+		b.WriteString("**/")
+	}
+
+	for i, segment := range p.Segments {
+		if i > 0 {
+			b.WriteByte('/')
+		}
+		segment.appendTo(&b)
+	}
+	if p.DirOnly {
+		b.WriteByte('/')
+	}
+
+	s := b.String()
+	if !p.Negate && s != "" && (s[0] == '#' || s[0] == '!') {
+		// it would be a comment or a negation otherwise
+		s = "\\" + s
+	}
+	if strings.HasSuffix(s, " ") {
+		// trailing spaces are removed unless the last one is escaped
+		s = s[:len(s)-1] + "\\ "
+	}
+	if p.Negate {
+		s = "!" + s
+	}
+	return s
+}
+
+func (segment *Segment) appendTo(b *strings.Builder) {
+	if segment.DoubleStar {
+		b.WriteString("**")
+		return
+	}
+
+	for i, node := range segment.Nodes {
+		switch node := node.(type) {
+		case Literal:
+			for j := 0; j < len(node.Text); j++ {
+				if strings.IndexByte("*?[\\", node.Text[j]) != -1 {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(node.Text[j])
+			}
+		case Star:
+			// "**" would be a DoubleStar, and it is the same as "*" within a segment anyway
+			if i == 0 || segment.Nodes[i-1] != (Star{}) {
+				b.WriteByte('*')
+			}
+		case Question:
+			b.WriteByte('?')
+		case Class:
+			node.appendTo(b)
+		}
+	}
+}
+
+func (class *Class) appendTo(b *strings.Builder) {
+	b.WriteByte('[')
+	if class.Negate {
+		b.WriteByte('!')
+	}
+	for _, item := range class.Items {
+		if item.Name != "" {
+			b.WriteString("[:" + item.Name + ":]")
+			continue
+		}
+		appendClassRange(b, int(item.Lo), int(item.Hi))
+	}
+	b.WriteByte(']')
+}
+
+// Characters which have to be escaped in a character class, so they aren't taken for
+// the end of the class, a negation, a range or the start of a POSIX class
+func specialInClass(c int) bool {
+	return strings.IndexByte("]\\-[!^", byte(c)) != -1
+}
+
+// The ends of a range can't be escaped, so the special characters at its ends are written separately
+func appendClassRange(b *strings.Builder, lo int, hi int) {
+	if lo > hi {
+		// matches nothing
+		b.WriteString(string([]byte{byte(lo), '-', byte(hi)}))
+		return
+	}
+
+	for lo <= hi && specialInClass(lo) {
+		b.WriteByte('\\')
+		b.WriteByte(byte(lo))
+		lo++
+	}
+	end := hi
+	for hi >= lo && specialInClass(hi) {
+		hi--
+	}
+
+	switch {
+	case lo == hi:
+		b.WriteByte(byte(lo))
+	case lo < hi:
+		b.WriteString(string([]byte{byte(lo), '-', byte(hi)}))
+	}
+	for c := hi + 1; c <= end; c++ {
+		if c >= lo {
+			b.WriteByte('\\')
+			b.WriteByte(byte(c))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package goignore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for line, expected := range map[string]*Pattern{
+		"*.o": {Segments: []Segment{
+			{Nodes: []Node{Star{}, Literal{Text: ".o"}}},
+		}},
+		"!/build/": {Negate: true, Anchored: true, DirOnly: true, Segments: []Segment{
+			{Nodes: []Node{Literal{Text: "build"}}},
+		}},
+		"docs/**/*.md  ": {Anchored: true, Segments: []Segment{
+			{Nodes: []Node{Literal{Text: "docs"}}},
+			{DoubleStar: true},
+			{Nodes: []Node{Star{}, Literal{Text: ".md"}}},
+		}},
+		"\\#a\\*b\\ ": {Segments: []Segment{
+			{Nodes: []Node{Literal{Text: "#a*b "}}},
+		}},
+		"file?.[!a-z_\\]][[:digit:]]": {Segments: []Segment{
+			{Nodes: []Node{
+				Literal{Text: "file"},
+				Question{},
+				Literal{Text: "."},
+				Class{Negate: true, Items: []ClassItem{{Lo: 'a', Hi: 'z'}, {Lo: '_', Hi: '_'}, {Lo: ']', Hi: ']'}}},
+				Class{Items: []ClassItem{{Name: "digit"}}},
+			}},
+		}},
+		"[]-]": {Segments: []Segment{
+			{Nodes: []Node{Class{Items: []ClassItem{{Lo: ']', Hi: ']'}, {Lo: '-', Hi: '-'}}}}},
+		}},
+		"a\\/b": {Anchored: true, Segments: []Segment{
+			{Nodes: []Node{Literal{Text: "a\\"}}},
+			{Nodes: []Node{Literal{Text: "b"}}},
+		}},
+	} {
+		pattern, err := Parse(line)
+		assert.NoError(t, err, line)
+		assert.Equal(t, expected, pattern, line)
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "!", "/", "!/", "//", "!///"} {
+		_, err := Parse(line)
+		assert.ErrorIs(t, err, ErrNoPattern, line)
+	}
+
+	_, err := Parse("foo[a-z")
+	assert.EqualError(t, err, ":1:4: unclosed character class: foo[a-z")
+	_, err = Parse("!x\\")
+	assert.EqualError(t, err, ":1:3: trailing backslash: !x\\")
+}
+
+func TestPattern_String(t *testing.T) {
+	for line, expected := range map[string]string{
+		"*.o":             "*.o",
+		"!/build/":        "!/build/",
+		"/a/b":            "a/b",
+		"docs/**/*.md":    "docs/**/*.md",
+		"\\#a\\*b\\ \\ ":  "\\#a\\*b \\ ",
+		"\\!x":            "\\!x",
+		"!!x":             "!!x",
+		"a**b":            "a*b",
+		"[!a-z_\\]]":      "[!a-z_\\]]",
+		"[]-]":            "[\\]\\-]",
+		"[!-a]":           "[!\\-a]",
+		"[a!-#]":          "[a\\!\"-#]",
+		"[Z-^]":           "[Z\\[\\\\\\]\\^]",
+		"[[:alpha:]\\[]x": "[[:alpha:]\\[]x",
+		"a\\/b":           "a\\\\/b",
+	} {
+		pattern, err := Parse(line)
+		assert.NoError(t, err, line)
+		assert.Equal(t, expected, pattern.String(), line)
+	}
+
+	// patterns which can't be parsed from a line
+	assert.Equal(t, "**/a/b", (&Pattern{Segments: []Segment{
+		{Nodes: []Node{Literal{Text: "a"}}},
+		{Nodes: []Node{Literal{Text: "b"}}},
+	}}).String())
+	assert.Equal(t, "\\*\\*", (&Pattern{Segments: []Segment{
+		{Nodes: []Node{Literal{Text: "**"}}},
+	}}).String())
+}
+
+// Paths which exercise the patterns printed by Pattern.String()
+var patternTestPaths = []string{
+	"a", "b", "a/b", "x/a/b", "#a*b  ", "!x", "ab", "a--b", "]", "-", "^", "\\", "[", "Z", "_", "5", "a\\/b", "x.o", "docs/x/y.md",
+}
+
+func FuzzParse(f *testing.F) {
+	for _, line := range []string{"*.o", "!/build/", "docs/**/*.md", "[!a-z_\\]]", "[Z-^]", "\\#a\\*b\\ ", "a\\/b"} {
+		f.Add(line, "a/b")
+	}
+	f.Fuzz(func(t *testing.T, line string, path string) {
+		if strings.ContainsAny(line, "\r\n") {
+			// not a single line
+			return
+		}
+		pattern, err := Parse(line)
+		if err != nil {
+			return
+		}
+		printed := pattern.String()
+
+		reparsed, err := Parse(printed)
+		if !assert.NoError(t, err, "%q printed as %q", line, printed) {
+			return
+		}
+		assert.Equal(t, printed, reparsed.String(), "%q printed as %q", line, printed)
+
+		original := CompileIgnoreLines("*", "!*/", line)
+		rewritten := CompileIgnoreLines("*", "!*/", printed)
+		for _, path := range append(patternTestPaths, path) {
+			assert.Equal(t, original.MatchesPath(path), rewritten.MatchesPath(path), "%q printed as %q, for %q", line, printed, path)
+		}
+	})
+}
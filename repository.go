@@ -0,0 +1,198 @@
+package goignore
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Stores the rules of every .gitignore file in a directory tree
+// Each file only applies to paths below the directory it is in, and deeper files take precedence
+// It is safe for concurrent use once it is created
+type Repository struct {
+	// one layer for each file, with the directory it is in as the base directory
+	// parents always come before their subdirectories
+	stack Stack
+	// used for compiling every file
+	options compileOptions
+}
+
+// Creates a Repository from every .gitignore file below root, each file is compiled with opts
+// Directories that are ignored are not descended into, just like git does
+func CompileRepository(root string, opts ...Option) (*Repository, error) {
+	return CompileRepositoryFS(os.DirFS(root), opts...)
+}
+
+// Same as CompileRepository, but finds the .gitignore files in fsys, which is the root of the repository
+func CompileRepositoryFS(fsys fs.FS, opts ...Option) (*Repository, error) {
+	repo := &Repository{
+		options: newCompileOptions(opts),
+	}
+
+	err := repo.loadTree(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Adds every .gitignore file in fsys to the repository
+func (r *Repository) loadTree(fsys fs.FS) error {
+	return walkDir(fsys, ".", r, func(path string, d fs.DirEntry, err error) error {
+		return err
+	})
+}
+
+// Creates a Repository from the same files `git ls-files --exclude-standard` uses, from lowest to highest precedence:
+// the file named by core.excludesFile (by default $XDG_CONFIG_HOME/git/ignore), $GIT_DIR/info/exclude,
+// and every .gitignore file below root
+// core.excludesFile is read from the global config files and the config of the repository, git itself is not needed
+// core.ignoreCase=true has the same effect as WithCaseFold()
+func CompileRepositoryStandard(root string, opts ...Option) (*Repository, error) {
+	gitDir, err := findGitDir(root)
+	if err != nil {
+		return nil, err
+	}
+	commonDir := findCommonDir(gitDir)
+
+	config, err := readGitConfigFiles(append(globalGitConfigFiles(), filepath.Join(commonDir, "config"))...)
+	if err != nil {
+		return nil, err
+	}
+
+	excludesFile, ok := config["core.excludesfile"]
+	if ok {
+		excludesFile = expandHome(excludesFile)
+		if excludesFile != "" && !filepath.IsAbs(excludesFile) {
+			excludesFile = filepath.Join(root, excludesFile)
+		}
+	} else {
+		excludesFile = xdgConfigPath("ignore")
+	}
+
+	repo := &Repository{
+		options: newCompileOptions(opts),
+	}
+	if parseConfigBool(config["core.ignorecase"]) {
+		repo.options.caseFold = true
+	}
+	if excludesFile != "" {
+		if err := repo.loadExcludeFile(excludesFile, filepath.ToSlash(excludesFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	infoExclude := filepath.Join(commonDir, "info", "exclude")
+	source := filepath.ToSlash(infoExclude)
+	if rel, err := filepath.Rel(root, infoExclude); err == nil && filepath.IsLocal(rel) {
+		source = filepath.ToSlash(rel)
+	}
+	if err := repo.loadExcludeFile(infoExclude, source); err != nil {
+		return nil, err
+	}
+
+	err = repo.loadTree(os.DirFS(root))
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Adds a file of patterns that apply to the whole repository, if it exists
+// These have lower precedence than all .gitignore files, so they must be loaded first
+func (r *Repository) loadExcludeFile(filename string, source string) error {
+	file, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ignore, err := compileLayer(file, ".", source, r.options)
+	if err != nil {
+		return err
+	}
+
+	r.stack.Push(ignore)
+	return nil
+}
+
+// Compiles an ignore file which applies below dir, as a layer of a repository
+// source is the name of the file in the matches, relative to the root of the repository if it is in it
+func compileLayer(file io.Reader, dir string, source string, options compileOptions) (*GitIgnore, error) {
+	options.source = source
+	options.baseDir = dir
+	options.dirCacheSize = 0 // only the rules of the layers are used, not their caches
+
+	return compileReader(file, options)
+}
+
+// Adds the .gitignore file of a directory to the repository, if it has one
+// name is the path of the directory in fsys, dir is its path relative to the root of the repository
+// Subdirectories have to be loaded after their parents
+func (r *Repository) loadDir(fsys fs.FS, name string, dir string) error {
+	ignore, err := compileDirIgnore(fsys, name, dir, r.options)
+	if err != nil || ignore == nil {
+		return err
+	}
+
+	r.stack.Push(ignore)
+	return nil
+}
+
+// Compiles the .gitignore file of a directory with dir as its base directory, or returns nil if it has none
+// name is the path of the directory in fsys, dir is its path relative to the root of the repository
+func compileDirIgnore(fsys fs.FS, name string, dir string, options compileOptions) (*GitIgnore, error) {
+	file, err := fsys.Open(path.Join(name, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// name the source like git does, relative to the root of the repository
+	source := ".gitignore"
+	if dir != "." {
+		source = dir + "/.gitignore"
+	}
+	return compileLayer(file, dir, source, options)
+}
+
+// Tries to match the path, relative to the root of the repository, to the rules in the .gitignore files
+// The path is treated as a directory if it has a trailing slash
+func (r *Repository) MatchesPath(path string) bool {
+	return r.stack.MatchesPath(path)
+}
+
+// Same as MatchesPath, but isDir tells whether the path is a directory, a trailing slash makes no difference
+func (r *Repository) MatchesPathIsDir(path string, isDir bool) bool {
+	return r.stack.MatchesPathIsDir(path, isDir)
+}
+
+// Returns the rule which decides whether the path is ignored, or nil if no rule matches it
+// The Source of the returned Match is relative to the root of the repository
+func (r *Repository) Explain(path string) *Match {
+	return r.stack.Explain(path)
+}
+
+// Same as MatchesPath, but tells apart paths re-included by a negated rule from paths no rule matched
+func (r *Repository) Check(path string) Result {
+	return r.stack.Check(path)
+}
+
+// Same as Check, but isDir tells whether the path is a directory, a trailing slash makes no difference
+func (r *Repository) CheckIsDir(path string, isDir bool) Result {
+	return r.stack.CheckIsDir(path, isDir)
+}
+
+// Returns the problems found in malformed patterns of all the .gitignore files
+func (r *Repository) Warnings() []*PatternError {
+	return r.stack.Warnings()
+}
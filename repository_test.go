@@ -0,0 +1,268 @@
+package goignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates the files in a temporary directory and returns its path
+// Names ending in '/' are created as empty directories
+func createTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			assert.NoError(t, os.MkdirAll(path, 0755))
+			continue
+		}
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return root
+}
+
+func TestRepository_NestedFiles(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".gitignore":       "*.log\n/foo\n",
+		"a/b/.gitignore":   "/foo\n!keep.log\n",
+		"a/b/c/.gitignore": "bar\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+	assert.NotNil(t, repo, "Returned object should not be nil")
+
+	assert.Equal(t, true, repo.MatchesPath("foo"), "foo should match")
+	assert.Equal(t, true, repo.MatchesPath("a/debug.log"), "a/debug.log should match")
+	assert.Equal(t, false, repo.MatchesPath("a/foo"), "a/foo should not match")
+	assert.Equal(t, true, repo.MatchesPath("a/b/foo"), "a/b/foo should match")
+	assert.Equal(t, false, repo.MatchesPath("a/b/c/foo"), "a/b/c/foo should not match")
+	assert.Equal(t, false, repo.MatchesPath("a/b/keep.log"), "a/b/keep.log should not match")
+	assert.Equal(t, false, repo.MatchesPath("a/b/c/keep.log"), "a/b/c/keep.log should not match")
+	assert.Equal(t, true, repo.MatchesPath("a/keep.log"), "a/keep.log should match")
+	assert.Equal(t, false, repo.MatchesPath("bar"), "bar should not match")
+	assert.Equal(t, false, repo.MatchesPath("a/b/bar"), "a/b/bar should not match")
+	assert.Equal(t, true, repo.MatchesPath("a/b/c/bar"), "a/b/c/bar should match")
+	assert.Equal(t, true, repo.MatchesPath("a/b/c/d/bar/file"), "a/b/c/d/bar/file should match")
+}
+
+func TestRepository_IgnoredParentCannotBeReincluded(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".gitignore":       "build/\n",
+		"build/.gitignore": "!*\n",
+		"src/.gitignore":   "!/build/\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, repo.MatchesPath("build/"), "build/ should match")
+	assert.Equal(t, true, repo.MatchesPath("build/out.o"), "build/out.o should match")
+	assert.Equal(t, false, repo.MatchesPath("src/build/"), "src/build/ should not match")
+	assert.Equal(t, false, repo.MatchesPath("src/build/out.o"), "src/build/out.o should not match")
+	assert.Equal(t, true, repo.MatchesPath("src/a/build/out.o"), "src/a/build/out.o should match")
+}
+
+func TestRepository_SkipsGitDirectory(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".git/.gitignore": "*\n",
+		"a/.gitignore":    "b\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, false, repo.MatchesPath("file.txt"), "file.txt should not match")
+	assert.Equal(t, true, repo.MatchesPath("a/b"), "a/b should match")
+}
+
+func TestRepository_Explain(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".gitignore":     "# build output\nbuild/\n*.o\n!keep.o\n",
+		"sub/.gitignore": "/gen\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ".gitignore:2:build/", repo.Explain("build/x/y").String())
+	assert.Equal(t, ".gitignore:4:!keep.o", repo.Explain("keep.o").String())
+	assert.Equal(t, ".gitignore:3:*.o", repo.Explain("a.o").String())
+	assert.Equal(t, "sub/.gitignore:1:/gen", repo.Explain("sub/gen/f").String())
+	assert.Equal(t, "sub/.gitignore:1:/gen", repo.Explain("sub/gen").String())
+	assert.Nil(t, repo.Explain("nothing"), "nothing should not match any rule")
+}
+
+func TestRepository_MatchesPathIsDir(t *testing.T) {
+	root := createTree(t, map[string]string{
+		"a/.gitignore": "build/\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, repo.MatchesPathIsDir("a/build", true), "a/build should match as a directory")
+	assert.Equal(t, false, repo.MatchesPathIsDir("a/build", false), "a/build should not match as a file")
+	assert.Equal(t, false, repo.MatchesPathIsDir("build", true), "build should not match")
+}
+
+func TestRepository_Check(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".gitignore":     "*.o\n",
+		"sub/.gitignore": "!keep.o\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, Ignored, repo.Check("a.o"))
+	assert.Equal(t, Ignored, repo.Check("keep.o"))
+	assert.Equal(t, Included, repo.Check("sub/keep.o"))
+	assert.Equal(t, NoMatch, repo.Check("sub/a.c"))
+	assert.Equal(t, Included, repo.CheckIsDir("sub/keep.o", true))
+}
+
+func TestRepository_Warnings(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".gitignore":     "ok\n",
+		"sub/.gitignore": "ok\n[a-z\n",
+	})
+
+	repo, err := CompileRepository(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*PatternError{
+		{Source: "sub/.gitignore", Line: 2, Column: 1, Pattern: "[a-z", Reason: "unclosed character class"},
+	}, repo.Warnings())
+}
+
+// Points HOME and XDG_CONFIG_HOME into a temporary directory, so the config of the user running the tests is not read
+func isolateGitConfig(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	return home
+}
+
+func TestCompileRepositoryStandard(t *testing.T) {
+	home := isolateGitConfig(t)
+	assert.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "git"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".config", "git", "ignore"), []byte("*.xdg\n"), 0644))
+
+	root := createTree(t, map[string]string{
+		".git/info/exclude": "*.exclude\n!*.xdg\n",
+		".gitignore":        "!*.exclude\n",
+		"sub/.gitignore":    "a.xdg\n",
+	})
+
+	repo, err := CompileRepositoryStandard(root)
+	assert.NoError(t, err)
+
+	// info/exclude takes precedence over the global excludes file, and .gitignore files over both
+	assert.Equal(t, false, repo.MatchesPath("a.xdg"), "a.xdg should not match")
+	assert.Equal(t, ".git/info/exclude:2:!*.xdg", repo.Explain("a.xdg").String())
+	assert.Equal(t, false, repo.MatchesPath("a.exclude"), "a.exclude should not match")
+	assert.Equal(t, ".gitignore:1:!*.exclude", repo.Explain("a.exclude").String())
+	assert.Equal(t, true, repo.MatchesPath("sub/a.xdg"), "sub/a.xdg should match")
+}
+
+func TestCompileRepositoryStandard_ExcludesFile(t *testing.T) {
+	home := isolateGitConfig(t)
+	assert.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "git"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".config", "git", "ignore"), []byte("*.xdg\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[core]\n\texcludesFile = ~/global-ignore\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, "global-ignore"), []byte("*.global\n"), 0644))
+
+	root := createTree(t, map[string]string{
+		".git/config": "[core]\n\tbare = false\n",
+	})
+
+	repo, err := CompileRepositoryStandard(root)
+	assert.NoError(t, err)
+
+	// core.excludesFile replaces the XDG default
+	assert.Equal(t, false, repo.MatchesPath("a.xdg"), "a.xdg should not match")
+	assert.Equal(t, true, repo.MatchesPath("a.global"), "a.global should match")
+	assert.Equal(t, filepath.ToSlash(filepath.Join(home, "global-ignore"))+":1:*.global", repo.Explain("a.global").String())
+
+	// the config of the repository overrides the global one
+	assert.NoError(t, os.WriteFile(filepath.Join(root, ".git", "config"), []byte("[core]\n\texcludesFile = local-ignore\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "local-ignore"), []byte("*.local\n"), 0644))
+
+	repo, err = CompileRepositoryStandard(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, false, repo.MatchesPath("a.global"), "a.global should not match")
+	assert.Equal(t, true, repo.MatchesPath("a.local"), "a.local should match")
+}
+
+func TestCompileRepositoryStandard_IgnoreCase(t *testing.T) {
+	isolateGitConfig(t)
+
+	root := createTree(t, map[string]string{
+		".git/config":       "[core]\n\tignoreCase = true\n",
+		".git/info/exclude": "*.O\n",
+		"Sub/.gitignore":    "/Build\n",
+	})
+
+	repo, err := CompileRepositoryStandard(root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, repo.MatchesPath("a.o"), "a.o should match")
+	assert.Equal(t, true, repo.MatchesPath("Sub/Build"), "Sub/Build should match")
+	assert.Equal(t, true, repo.MatchesPath("sub/build"), "sub/build should match")
+	assert.Equal(t, true, repo.MatchesPath("SUB/BUILD/x"), "SUB/BUILD/x should match")
+	assert.Equal(t, "Sub/.gitignore:1:/Build", repo.Explain("SUB/BUILD/x").String())
+}
+
+func TestCompileRepositoryStandard_NotARepository(t *testing.T) {
+	isolateGitConfig(t)
+
+	repo, err := CompileRepositoryStandard(t.TempDir())
+	assert.Error(t, err)
+	assert.Nil(t, repo)
+}
+
+func TestCompileRepository_Options(t *testing.T) {
+	root := createTree(t, map[string]string{
+		"Sub/.gitignore": "Foo\n",
+	})
+
+	repo, err := CompileRepository(root, WithCaseFold(), WithSource("ignored"))
+	assert.NoError(t, err)
+	assert.Equal(t, true, repo.MatchesPath("sub/foo"), "sub/foo should match")
+	assert.Equal(t, "Sub/.gitignore:1:Foo", repo.Explain("sub/foo").String())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "Sub", ".gitignore"), []byte("[a-z\n"), 0644))
+
+	repo, err = CompileRepository(root, WithStrict())
+	assert.EqualError(t, err, "Sub/.gitignore:1:1: unclosed character class: [a-z")
+	assert.Nil(t, repo)
+}
+
+func TestCompileRepositoryFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":       {Data: []byte("*.o\nbuild/\n")},
+		"a/.gitignore":     {Data: []byte("!keep.o\n")},
+		"build/.gitignore": {Data: []byte("!*\n")},
+	}
+
+	repo, err := CompileRepositoryFS(fsys)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, repo.MatchesPath("x.o"), "x.o should match")
+	assert.Equal(t, false, repo.MatchesPath("a/keep.o"), "a/keep.o should not match")
+	assert.Equal(t, true, repo.MatchesPath("build/keep.o"), "build/keep.o should match")
+	assert.Equal(t, 2, repo.stack.Len(), "build/.gitignore should not be loaded")
+}
+
+func TestRepository_MissingRoot(t *testing.T) {
+	repo, err := CompileRepository(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+	assert.Nil(t, repo)
+}
@@ -0,0 +1,149 @@
+package goignore
+
+// Combines several GitIgnores, like the global excludes, the .gitignore of a repository and the defaults of a tool
+// Layers are evaluated as if their rules were in one file: the last matching rule of the highest priority layer wins,
+// and a directory ignored by any layer can't have its contents re-included by another one
+// Each layer keeps its own options, e.g. base directory, case folding and source
+// It is safe for concurrent use, as long as Push() is not called at the same time
+type Stack struct {
+	// from lowest to highest priority
+	layers []*GitIgnore
+	// set if any of the layers folds case, the path has to be folded for those
+	caseFold bool
+}
+
+// Creates a Stack from the layers, from lowest to highest priority, nil layers are skipped
+func NewStack(layers ...*GitIgnore) *Stack {
+	s := &Stack{}
+	for _, layer := range layers {
+		s.Push(layer)
+	}
+	return s
+}
+
+// Adds a layer with higher priority than all the others, nil is skipped
+// Must not be called concurrently with matching
+func (s *Stack) Push(layer *GitIgnore) {
+	if layer == nil {
+		return
+	}
+	s.layers = append(s.layers, layer)
+	if layer.caseFold {
+		s.caseFold = true
+	}
+}
+
+// Returns a new Stack with the layer on top, s is not modified so it can still be in use
+func (s *Stack) withLayer(layer *GitIgnore) *Stack {
+	layers := make([]*GitIgnore, len(s.layers), len(s.layers)+1)
+	copy(layers, s.layers)
+	child := &Stack{layers: layers, caseFold: s.caseFold}
+	child.Push(layer)
+	return child
+}
+
+// Returns the number of layers in the stack
+func (s *Stack) Len() int {
+	return len(s.layers)
+}
+
+// Returns a folded copy of the path components if any layer needs it, otherwise nil
+func (s *Stack) fold(pathComponents []string) []string {
+	if !s.caseFold {
+		return nil
+	}
+	folded := make([]string, len(pathComponents))
+	copy(folded, pathComponents)
+	foldPathComponents(folded)
+	return folded
+}
+
+// Returns the last matching rule of the highest priority layer which has one
+// folded must be the result of fold() for the same path
+// Parent directories of the path are not checked
+func (s *Stack) lastMatchingRule(isDir bool, pathComponents []string, folded []string) *rule {
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		layer := s.layers[i]
+		components := pathComponents
+		if layer.caseFold {
+			components = folded
+		}
+
+		relativeComponents, ok := layer.relativeToBase(components)
+		if !ok {
+			continue
+		}
+
+		if rule := layer.lastMatchingRule(isDir, relativeComponents); rule != nil {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Returns the rule which decides whether the path is ignored, or nil if no rule matches it
+func (s *Stack) decidingRule(pathComponents []string, isDir bool) *rule {
+	folded := s.fold(pathComponents)
+
+	// If a parent directory is ignored, nothing inside of it can be re-included
+	for j := 0; j < len(pathComponents)-1; j++ {
+		var foldedParent []string
+		if folded != nil {
+			foldedParent = folded[:j+1]
+		}
+		rule := s.lastMatchingRule(true, pathComponents[:j+1], foldedParent)
+		if rule != nil && !rule.Negate {
+			return rule
+		}
+	}
+
+	return s.lastMatchingRule(isDir, pathComponents, folded)
+}
+
+// Tries to match the path to the rules of all the layers
+// The path is treated as a directory if it has a trailing slash
+func (s *Stack) MatchesPath(path string) bool {
+	return s.Check(path) == Ignored
+}
+
+// Same as MatchesPath, but isDir tells whether the path is a directory, a trailing slash makes no difference
+func (s *Stack) MatchesPathIsDir(path string, isDir bool) bool {
+	return s.CheckIsDir(path, isDir) == Ignored
+}
+
+// Same as MatchesPath, but tells apart paths re-included by a negated rule from paths no rule matched
+func (s *Stack) Check(path string) Result {
+	pathComponents, isDir, ok := splitPath(path)
+	if !ok {
+		return NoMatch
+	}
+	return s.decidingRule(pathComponents, isDir).toResult()
+}
+
+// Same as Check, but isDir tells whether the path is a directory, a trailing slash makes no difference
+func (s *Stack) CheckIsDir(path string, isDir bool) Result {
+	pathComponents, _, ok := splitPath(path)
+	if !ok {
+		return NoMatch
+	}
+	return s.decidingRule(pathComponents, isDir).toResult()
+}
+
+// Returns the rule which decides whether the path is ignored, or nil if no rule matches it
+// The Source of the returned Match tells which layer it came from
+func (s *Stack) Explain(path string) *Match {
+	pathComponents, isDir, ok := splitPath(path)
+	if !ok {
+		return nil
+	}
+	return s.decidingRule(pathComponents, isDir).toMatch()
+}
+
+// Returns the problems found in malformed patterns of all the layers
+func (s *Stack) Warnings() []*PatternError {
+	var warnings []*PatternError
+	for _, layer := range s.layers {
+		warnings = append(warnings, layer.Warnings()...)
+	}
+	return warnings
+}
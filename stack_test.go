@@ -0,0 +1,78 @@
+package goignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStack(t *testing.T) {
+	defaults := CompileIgnoreLines("*.log", "build/", "*.tmp")
+	repo, err := Compile([]string{"!important.log", "!*.tmp", "out/"}, WithSource(".gitignore"))
+	assert.NoError(t, err)
+	excludes, err := Compile([]string{"debug.tmp"}, WithSource("--exclude"))
+	assert.NoError(t, err)
+
+	stack := NewStack(defaults, repo, nil, excludes)
+	assert.Equal(t, 3, stack.Len())
+
+	assert.Equal(t, true, stack.MatchesPath("a.log"), "a.log should match")
+	assert.Equal(t, false, stack.MatchesPath("important.log"), "important.log should not match")
+	assert.Equal(t, Included, stack.Check("a.tmp"))
+	assert.Equal(t, Ignored, stack.Check("debug.tmp"))
+	assert.Equal(t, NoMatch, stack.Check("a.c"))
+	assert.Equal(t, true, stack.MatchesPathIsDir("out", true), "out should match as a directory")
+	assert.Equal(t, false, stack.MatchesPathIsDir("out", false), "out should not match as a file")
+	assert.Equal(t, Ignored, stack.CheckIsDir("build", true))
+
+	assert.Equal(t, ".gitignore:1:!important.log", stack.Explain("important.log").String())
+	assert.Equal(t, "--exclude:1:debug.tmp", stack.Explain("debug.tmp").String())
+	assert.Equal(t, ":1:*.log", stack.Explain("a.log").String())
+}
+
+func TestStack_IgnoredParentCannotBeReincluded(t *testing.T) {
+	stack := NewStack(
+		CompileIgnoreLines("build/"),
+		CompileIgnoreLines("!build/x", "!build/"),
+	)
+	assert.Equal(t, false, stack.MatchesPath("build/x"), "build/x should not match, build/ is re-included")
+
+	stack = NewStack(
+		CompileIgnoreLines("build/"),
+		CompileIgnoreLines("!build/x"),
+	)
+	assert.Equal(t, true, stack.MatchesPath("build/x"), "build/x should match")
+	assert.Equal(t, ":1:build/", stack.Explain("build/x").String())
+
+	// the higher layer excludes the parent, the lower one can't re-include the contents
+	stack = NewStack(
+		CompileIgnoreLines("!*.c"),
+		CompileIgnoreLines("src/"),
+	)
+	assert.Equal(t, true, stack.MatchesPath("src/a.c"), "src/a.c should match")
+}
+
+func TestStack_LayerOptions(t *testing.T) {
+	folded, err := Compile([]string{"*.O"}, WithCaseFold())
+	assert.NoError(t, err)
+	sub, err := Compile([]string{"/Gen"}, WithBaseDir("Sub"))
+	assert.NoError(t, err)
+
+	stack := NewStack(folded, sub)
+
+	assert.Equal(t, true, stack.MatchesPath("Sub/A.o"), "Sub/A.o should match")
+	assert.Equal(t, true, stack.MatchesPath("Sub/Gen/x"), "Sub/Gen/x should match")
+	assert.Equal(t, false, stack.MatchesPath("sub/gen/x"), "sub/gen/x should not match, only the first layer folds case")
+	assert.Equal(t, false, stack.MatchesPath("Gen"), "Gen should not match")
+}
+
+func TestStack_Warnings(t *testing.T) {
+	first, _ := Compile([]string{"[a-z"}, WithSource("first"))
+	second, _ := Compile([]string{"ok", "foo\\"}, WithSource("second"))
+
+	warnings := NewStack(first, second).Warnings()
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, "first", warnings[0].Source)
+	assert.Equal(t, "second", warnings[1].Source)
+	assert.Equal(t, 0, len(NewStack().Warnings()))
+}
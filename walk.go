@@ -0,0 +1,62 @@
+package goignore
+
+import (
+	"io/fs"
+)
+
+// Walks the file tree rooted at root like fs.WalkDir, but leaves out the entries ignored by .gitignore files
+// The .gitignore files are picked up as the tree is descended, paths are matched relative to root
+// Ignored directories are not descended into, and .git directories are always skipped
+func WalkDir(fsys fs.FS, root string, fn fs.WalkDirFunc) error {
+	return walkDir(fsys, root, &Repository{}, fn)
+}
+
+// Same as WalkDir, but collects the .gitignore files into repo
+func walkDir(fsys fs.FS, root string, repo *Repository, fn fs.WalkDirFunc) error {
+	return fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fn(name, d, err)
+		}
+
+		rel := relativePath(root, name)
+		if rel != "." {
+			if d.IsDir() && d.Name() == ".git" {
+				return fs.SkipDir
+			}
+
+			// The parent directories were not ignored, otherwise we would not be here.
+			pathComponents := splitCleanPath(rel)
+			rule := repo.stack.lastMatchingRule(d.IsDir(), pathComponents, repo.stack.fold(pathComponents))
+			if rule != nil && !rule.Negate {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if err := fn(name, d, nil); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if err := repo.loadDir(fsys, name, rel); err != nil {
+				// report it like fs.WalkDir reports directories which can't be read
+				return fn(name, d, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Returns the path of name relative to root, both must be valid fs.FS paths
+func relativePath(root string, name string) string {
+	switch {
+	case name == root:
+		return "."
+	case root == ".":
+		return name
+	default:
+		return name[len(root)+1:]
+	}
+}
@@ -0,0 +1,275 @@
+package goignore
+
+import (
+	"io/fs"
+	"path"
+	"runtime"
+	"sync"
+)
+
+// Configures WalkParallel
+type WalkOption func(*walkOptions)
+
+type walkOptions struct {
+	workers  int
+	ordered  bool
+	excludes []*GitIgnore
+}
+
+// Sets how many directories are read at the same time, it is runtime.GOMAXPROCS(0) by default
+func WithWorkers(n int) WalkOption {
+	return func(options *walkOptions) {
+		options.workers = n
+	}
+}
+
+// Makes WalkParallel call fn in the same order as WalkDir does, which is lexical order
+// Directories are still read ahead in parallel, but a slow directory holds back the ones after it
+func WithOrdered() WalkOption {
+	return func(options *walkOptions) {
+		options.ordered = true
+	}
+}
+
+// Adds patterns which apply to the whole tree with lower precedence than the .gitignore files, like info/exclude does
+// Paths are matched against them relative to root
+func WithExcludes(excludes ...*GitIgnore) WalkOption {
+	return func(options *walkOptions) {
+		options.excludes = append(options.excludes, excludes...)
+	}
+}
+
+// Same as WalkDir, but reads directories, loads their .gitignore files and matches their entries in parallel
+// fn is never called concurrently, and a directory is always passed to it before its contents,
+// but the order is unspecified otherwise, unless WithOrdered() is given
+// fn can prune the walk like with fs.WalkDir, by returning fs.SkipDir or fs.SkipAll
+func WalkParallel(fsys fs.FS, root string, fn fs.WalkDirFunc, opts ...WalkOption) error {
+	options := walkOptions{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.workers < 1 {
+		options.workers = 1
+	}
+
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		w := &parallelWalker{
+			fsys: fsys,
+			root: root,
+			fn:   fn,
+			sem:  make(chan struct{}, options.workers),
+			done: make(chan struct{}),
+		}
+		d := fs.FileInfoToDirEntry(info)
+		stack := NewStack(options.excludes...)
+
+		if options.ordered {
+			err = w.walkOrdered(root, d, stack)
+		} else {
+			w.results = make(chan *walkListing)
+			err = w.walkUnordered(root, d, stack)
+		}
+
+		// let the reads which are still running finish, nothing waits for their results anymore
+		close(w.done)
+		w.wg.Wait()
+	}
+
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+type parallelWalker struct {
+	fsys fs.FS
+	root string
+	fn   fs.WalkDirFunc
+
+	sem  chan struct{} // limits the number of directories read at the same time
+	done chan struct{} // closed when the walk is over
+	wg   sync.WaitGroup
+
+	// the listings are sent here when they are read, only used without WithOrdered()
+	results chan *walkListing
+}
+
+// A directory which is read by a worker
+type walkListing struct {
+	name string // the path of the directory in fsys
+	d    fs.DirEntry
+
+	// the rules which apply to the contents, including the .gitignore of the directory
+	stack *Stack
+	// the entries which are not ignored, in lexical order
+	entries []fs.DirEntry
+	loadErr error // from reading the .gitignore file
+	readErr error // from reading the directory, entries may still be partially filled in
+
+	ready chan struct{} // closed when the fields above are filled in
+}
+
+// Starts reading the directory in the background, parent holds the rules which apply to it
+func (w *parallelWalker) start(name string, d fs.DirEntry, parent *Stack) *walkListing {
+	listing := &walkListing{
+		name:  name,
+		d:     d,
+		ready: make(chan struct{}),
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(listing.ready)
+
+		select {
+		case w.sem <- struct{}{}:
+		case <-w.done:
+			return
+		}
+		listing.read(w.fsys, relativePath(w.root, name), parent)
+		<-w.sem
+
+		if w.results != nil {
+			select {
+			case w.results <- listing:
+			case <-w.done:
+			}
+		}
+	}()
+	return listing
+}
+
+// Loads the .gitignore file and the entries of the directory which aren't ignored
+// rel is the path of the directory relative to the root of the walk
+func (listing *walkListing) read(fsys fs.FS, rel string, parent *Stack) {
+	listing.stack = parent
+	ignore, err := compileDirIgnore(fsys, listing.name, rel, compileOptions{})
+	if err != nil {
+		listing.loadErr = err
+	} else if ignore != nil {
+		listing.stack = parent.withLayer(ignore)
+	}
+
+	entries, err := fs.ReadDir(fsys, listing.name)
+	listing.readErr = err
+
+	dirComponents := splitCleanPath(rel)
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() == ".git" {
+			continue
+		}
+
+		// The parent directories were not ignored, otherwise we would not be here.
+		pathComponents := append(dirComponents[:len(dirComponents):len(dirComponents)], entry.Name())
+		rule := listing.stack.lastMatchingRule(entry.IsDir(), pathComponents, listing.stack.fold(pathComponents))
+		if rule != nil && !rule.Negate {
+			continue
+		}
+		listing.entries = append(listing.entries, entry)
+	}
+}
+
+// Reports the errors of reading the directory to fn, like fs.WalkDir does
+// fs.SkipDir is passed on, it skips the directory
+func (w *parallelWalker) reportErrors(listing *walkListing) error {
+	for _, err := range []error{listing.loadErr, listing.readErr} {
+		if err == nil {
+			continue
+		}
+		if err := w.fn(listing.name, listing.d, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walks the tree depth first in lexical order, reading the subdirectories of each directory ahead of time
+func (w *parallelWalker) walkOrdered(name string, d fs.DirEntry, parent *Stack) error {
+	if err := w.fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	return w.walkListingOrdered(w.start(name, d, parent))
+}
+
+// Passes the contents of the directory to fn, and walks the subdirectories fn doesn't skip
+func (w *parallelWalker) walkListingOrdered(listing *walkListing) error {
+	<-listing.ready
+	if err := w.reportErrors(listing); err != nil {
+		if err == fs.SkipDir {
+			err = nil
+		}
+		return err
+	}
+
+	subdirectories := make([]*walkListing, len(listing.entries))
+	for i, entry := range listing.entries {
+		if entry.IsDir() {
+			subdirectories[i] = w.start(path.Join(listing.name, entry.Name()), entry, listing.stack)
+		}
+	}
+
+	for i, entry := range listing.entries {
+		name := path.Join(listing.name, entry.Name())
+		err := w.fn(name, entry, nil)
+		if err == nil && entry.IsDir() {
+			err = w.walkListingOrdered(subdirectories[i])
+		} else if err == fs.SkipDir && entry.IsDir() {
+			err = nil
+		}
+
+		if err == fs.SkipDir {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walks the tree in the order the directories are read in
+func (w *parallelWalker) walkUnordered(root string, d fs.DirEntry, stack *Stack) error {
+	if err := w.fn(root, d, nil); err != nil || !d.IsDir() {
+		return err
+	}
+
+	w.start(root, d, stack)
+	for pending := 1; pending > 0; pending-- {
+		listing := <-w.results
+
+		err := w.reportErrors(listing)
+		entries := listing.entries
+		if err != nil {
+			entries = nil
+		}
+
+		for _, entry := range entries {
+			name := path.Join(listing.name, entry.Name())
+			err = w.fn(name, entry, nil)
+			switch {
+			case err == nil && entry.IsDir():
+				w.start(name, entry, listing.stack)
+				pending++
+			case err == fs.SkipDir && entry.IsDir():
+				err = nil
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		// fs.SkipDir from a file skips the rest of its directory
+		if err != nil && err != fs.SkipDir {
+			return err
+		}
+	}
+	return nil
+}
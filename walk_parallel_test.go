@@ -0,0 +1,249 @@
+package goignore
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the paths WalkParallel visits, directories have a trailing slash
+// Also checks that fn is never called concurrently, and that directories come before their contents
+func walkedPathsParallel(t *testing.T, fsys fs.FS, root string, opts ...WalkOption) []string {
+	var paths []string
+	seen := map[string]bool{}
+	var running int32
+
+	err := WalkParallel(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if atomic.AddInt32(&running, 1) != 1 {
+			t.Errorf("fn was called concurrently for %q", name)
+		}
+		defer atomic.AddInt32(&running, -1)
+
+		if err != nil {
+			return err
+		}
+		if name != root {
+			parent := name[:strings.LastIndexByte(name, '/')+1]
+			if parent == "" {
+				parent = "./"
+			}
+			assert.True(t, seen[parent], "%q was visited before its directory", name)
+		}
+		if d.IsDir() {
+			name += "/"
+		}
+		seen[name] = true
+		paths = append(paths, name)
+		return nil
+	}, opts...)
+	assert.NoError(t, err)
+	return paths
+}
+
+// A tree with many directories and nested .gitignore files
+func largeTestTree() fstest.MapFS {
+	fsys := fstest.MapFS{
+		".gitignore": {Data: []byte("*.o\nbuild/\n/vendor\n")},
+		"vendor/x":   {},
+	}
+	for i := 0; i < 20; i++ {
+		dir := "pkg" + strconv.Itoa(i) + "/"
+		fsys[dir+".gitignore"] = &fstest.MapFile{Data: []byte("!keep.o\n/gen\n")}
+		for j := 0; j < 10; j++ {
+			sub := dir + "sub" + strconv.Itoa(j) + "/"
+			fsys[sub+"a.c"] = &fstest.MapFile{}
+			fsys[sub+"a.o"] = &fstest.MapFile{}
+			fsys[sub+"keep.o"] = &fstest.MapFile{}
+			fsys[sub+"build/out"] = &fstest.MapFile{}
+			fsys[sub+"gen/x.c"] = &fstest.MapFile{}
+		}
+		fsys[dir+"gen/x.c"] = &fstest.MapFile{}
+	}
+	return fsys
+}
+
+func TestWalkParallel(t *testing.T) {
+	for _, fsys := range []fstest.MapFS{largeTestTree(), {
+		".gitignore":            {Data: []byte("*.o\nbuild/\n")},
+		".git/config":           {},
+		"main.c":                {},
+		"main.o":                {},
+		"build/out":             {},
+		"src/.gitignore":        {Data: []byte("/gen\n!keep.o\n")},
+		"src/gen/a.c":           {},
+		"src/keep.o":            {},
+		"src/lib/gen/b.c":       {},
+		"vendor/.gitignore":     {Data: []byte("*\n!.gitignore\n")},
+		"vendor/lib/.gitignore": {Data: []byte("!*\n")},
+	}} {
+		expected := walkedPaths(t, fsys, ".")
+
+		for _, workers := range []int{1, 2, 16} {
+			ordered := walkedPathsParallel(t, fsys, ".", WithWorkers(workers), WithOrdered())
+			assert.Equal(t, expected, ordered, "ordered with %d workers", workers)
+
+			unordered := walkedPathsParallel(t, fsys, ".", WithWorkers(workers))
+			sort.Strings(unordered)
+			sorted := append([]string{}, expected...)
+			sort.Strings(sorted)
+			assert.Equal(t, sorted, unordered, "unordered with %d workers", workers)
+		}
+	}
+}
+
+func TestWalkParallel_Subdirectory(t *testing.T) {
+	fsys := fstest.MapFS{
+		"repo/.gitignore":   {Data: []byte("/a\n")},
+		"repo/a":            {},
+		"repo/b/a":          {},
+		"repo/b/.gitignore": {Data: []byte("/c\n")},
+		"repo/b/c/file.txt": {},
+		"other/file":        {},
+	}
+
+	assert.Equal(t, walkedPaths(t, fsys, "repo"), walkedPathsParallel(t, fsys, "repo", WithOrdered()))
+	assert.Equal(t, []string{"repo/a"}, walkedPathsParallel(t, fsys, "repo/a"))
+}
+
+func TestWalkParallel_Excludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore": {Data: []byte("!keep.log\n")},
+		"a.log":      {},
+		"keep.log":   {},
+		"tmp/x":      {},
+	}
+
+	excludes := CompileIgnoreLines("*.log", "tmp/")
+	assert.Equal(t, []string{"./", ".gitignore", "keep.log"}, walkedPathsParallel(t, fsys, ".", WithOrdered(), WithExcludes(excludes)))
+}
+
+func TestWalkParallel_SkipDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/.gitignore": {Data: []byte("*\n")},
+		"a/file":       {},
+		"b/file":       {},
+		"b/z/file":     {},
+		"c/1":          {},
+		"c/2":          {},
+		"c/3":          {},
+	}
+
+	for _, opts := range [][]WalkOption{{WithOrdered()}, {}} {
+		var paths []string
+		err := WalkParallel(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			paths = append(paths, path)
+			switch path {
+			case "a":
+				return fs.SkipDir
+			case "c/2":
+				// skips the rest of c
+				return fs.SkipDir
+			}
+			return nil
+		}, opts...)
+
+		assert.NoError(t, err)
+		sort.Strings(paths)
+		assert.Equal(t, []string{".", "a", "b", "b/file", "b/z", "b/z/file", "c", "c/1", "c/2"}, paths)
+	}
+}
+
+func TestWalkParallel_SkipAll(t *testing.T) {
+	for _, opts := range [][]WalkOption{{WithOrdered()}, {}} {
+		var count int
+		err := WalkParallel(largeTestTree(), ".", func(path string, d fs.DirEntry, err error) error {
+			count++
+			if count == 10 {
+				return fs.SkipAll
+			}
+			return nil
+		}, opts...)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, count)
+	}
+}
+
+// Fails reading one directory
+type failingReadDirFS struct {
+	fs.FS
+	dir string
+}
+
+var errReadDir = errors.New("can't read directory")
+
+func (fsys failingReadDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == fsys.dir {
+		return nil, errReadDir
+	}
+	return fs.ReadDir(fsys.FS, name)
+}
+
+func TestWalkParallel_Errors(t *testing.T) {
+	for _, opts := range [][]WalkOption{{WithOrdered()}, {}} {
+		err := WalkParallel(fstest.MapFS{}, "missing", func(path string, d fs.DirEntry, err error) error {
+			return err
+		}, opts...)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+
+		stop := errors.New("stop")
+		err = WalkParallel(largeTestTree(), ".", func(path string, d fs.DirEntry, err error) error {
+			if path == "pkg3/sub4/a.c" {
+				return stop
+			}
+			return err
+		}, opts...)
+		assert.ErrorIs(t, err, stop)
+
+		// the error is reported for the directory, which can be skipped
+		fsys := failingReadDirFS{FS: fstest.MapFS{"a/b/file": {}, "c/file": {}}, dir: "a/b"}
+		var paths []string
+		err = WalkParallel(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				assert.ErrorIs(t, err, errReadDir)
+				paths = append(paths, path+" failed")
+				return fs.SkipDir
+			}
+			paths = append(paths, path)
+			return nil
+		}, opts...)
+		assert.NoError(t, err)
+		sort.Strings(paths)
+		assert.Equal(t, []string{".", "a", "a/b", "a/b failed", "c", "c/file"}, paths)
+
+		err = WalkParallel(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			return err
+		}, opts...)
+		assert.ErrorIs(t, err, errReadDir)
+	}
+}
+
+func BenchmarkWalkParallel(b *testing.B) {
+	fsys := largeTestTree()
+	fn := func(path string, d fs.DirEntry, err error) error {
+		return err
+	}
+
+	b.Run("WalkDir", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			WalkDir(fsys, ".", fn)
+		}
+	})
+	b.Run("WalkParallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			WalkParallel(fsys, ".", fn)
+		}
+	})
+	b.Run("WalkParallel ordered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			WalkParallel(fsys, ".", fn, WithOrdered())
+		}
+	})
+}
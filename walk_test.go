@@ -0,0 +1,123 @@
+package goignore
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the paths WalkDir visits, directories have a trailing slash
+func walkedPaths(t *testing.T, fsys fs.FS, root string) []string {
+	var paths []string
+	err := WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			path += "/"
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	assert.NoError(t, err)
+	return paths
+}
+
+func TestWalkDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":            {Data: []byte("*.o\nbuild/\n")},
+		".git/config":           {},
+		"main.c":                {},
+		"main.o":                {},
+		"build/out":             {},
+		"src/.gitignore":        {Data: []byte("/gen\n!keep.o\n")},
+		"src/gen/a.c":           {},
+		"src/keep.o":            {},
+		"src/lib/gen/b.c":       {},
+		"src/lib/build/x":       {},
+		"vendor/.gitignore":     {Data: []byte("*\n!.gitignore\n")},
+		"vendor/lib/.gitignore": {Data: []byte("!*\n")},
+		"vendor/lib/a.c":        {},
+	}
+
+	assert.Equal(t, []string{
+		"./",
+		".gitignore",
+		"main.c",
+		"src/",
+		"src/.gitignore",
+		"src/keep.o",
+		"src/lib/",
+		"src/lib/gen/",
+		"src/lib/gen/b.c",
+		"vendor/",
+		"vendor/.gitignore",
+	}, walkedPaths(t, fsys, "."))
+}
+
+func TestWalkDir_Subdirectory(t *testing.T) {
+	fsys := fstest.MapFS{
+		"repo/.gitignore":    {Data: []byte("/a\n")},
+		"repo/a":             {},
+		"repo/b/a":           {},
+		"other/.gitignore":   {Data: []byte("*\n")},
+		"repo/b/.gitignore":  {Data: []byte("/c\n")},
+		"repo/b/c/file.txt":  {},
+		"repo/b/d/c/file.go": {},
+	}
+
+	assert.Equal(t, []string{
+		"repo/",
+		"repo/.gitignore",
+		"repo/b/",
+		"repo/b/.gitignore",
+		"repo/b/a",
+		"repo/b/d/",
+		"repo/b/d/c/",
+		"repo/b/d/c/file.go",
+	}, walkedPaths(t, fsys, "repo"))
+}
+
+func TestWalkDir_SkipDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/.gitignore": {Data: []byte("*\n")},
+		"a/file":       {},
+		"b/file":       {},
+	}
+
+	var paths []string
+	err := WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		paths = append(paths, path)
+		if path == "a" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	// a/.gitignore must not be loaded when a is skipped
+	assert.Equal(t, []string{".", "a", "b", "b/file"}, paths)
+}
+
+func TestWalkDir_Errors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/file": {},
+	}
+
+	err := WalkDir(fsys, "missing", func(path string, d fs.DirEntry, err error) error {
+		return err
+	})
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	stop := errors.New("stop")
+	err = WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if path == "a/file" {
+			return stop
+		}
+		return err
+	})
+	assert.ErrorIs(t, err, stop)
+}